@@ -1,3 +1,8 @@
 data "idefix_ci" "example" {
   id = 1234
 }
+
+data "idefix_ci" "by_name" {
+  name       = "myci"
+  company_id = 1234
+}
@@ -0,0 +1,8 @@
+data "idefix_ci_types" "all" {}
+
+resource "idefix_ci" "example" {
+  name        = "myci"
+  company_id  = 1234
+  project_ids = [1, 2]
+  type_id     = data.idefix_ci_types.all.by_name["Server"]
+}
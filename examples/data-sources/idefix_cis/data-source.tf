@@ -0,0 +1,4 @@
+data "idefix_cis" "example" {
+  company_id = 1234
+  project_id = 5678
+}
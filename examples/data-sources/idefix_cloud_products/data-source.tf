@@ -0,0 +1 @@
+data "idefix_cloud_products" "all" {}
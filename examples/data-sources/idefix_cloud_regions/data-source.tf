@@ -0,0 +1,19 @@
+data "idefix_cloud_subscriptions" "example" {
+  company_id = 1234
+}
+
+data "idefix_cloud_products" "all" {}
+
+data "idefix_cloud_regions" "all" {}
+
+resource "idefix_ci" "example" {
+  name        = "myci"
+  company_id  = 1234
+  project_ids = [1, 2]
+
+  service_cloud {
+    subscription_id = data.idefix_cloud_subscriptions.example.by_name["my-subscription"]
+    product_id      = data.idefix_cloud_products.all.by_name["Virtual Machine"]
+    region_id       = data.idefix_cloud_regions.all.by_name["westeurope"]
+  }
+}
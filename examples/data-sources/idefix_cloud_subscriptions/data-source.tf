@@ -0,0 +1,3 @@
+data "idefix_cloud_subscriptions" "example" {
+  company_id = 1234
+}
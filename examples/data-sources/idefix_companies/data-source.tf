@@ -0,0 +1,3 @@
+data "idefix_companies" "example" {
+  name_filter = "mycompany"
+}
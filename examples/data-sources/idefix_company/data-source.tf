@@ -0,0 +1,9 @@
+data "idefix_company" "example" {
+  name = "My Company"
+}
+
+resource "idefix_project" "example" {
+  name            = "example"
+  company_id      = data.idefix_company.example.id
+  contract_number = "1234"
+}
@@ -0,0 +1,12 @@
+data "idefix_environments" "all" {}
+
+resource "idefix_ci" "example" {
+  name        = "myci"
+  company_id  = 1234
+  project_ids = [1, 2]
+
+  key_dates {
+    environment_ids = [data.idefix_environments.all.by_name["Production"]]
+    function_names  = ["Web"]
+  }
+}
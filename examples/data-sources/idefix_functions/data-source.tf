@@ -0,0 +1,5 @@
+data "idefix_functions" "all" {}
+
+output "functions" {
+  value = data.idefix_functions.all.functions
+}
@@ -0,0 +1,14 @@
+data "idefix_monitoring_tools" "all" {}
+
+data "idefix_required_services" "all" {}
+
+resource "idefix_ci" "example" {
+  name        = "myci"
+  company_id  = 1234
+  project_ids = [1, 2]
+
+  service_at {
+    required_services = [data.idefix_required_services.all.by_name["Backup"]]
+    monitoring_tool   = [data.idefix_monitoring_tools.all.by_name["Centreon"]]
+  }
+}
@@ -0,0 +1,5 @@
+data "idefix_outsourcing_levels" "all" {}
+
+output "outsourcing_names" {
+  value = data.idefix_outsourcing_levels.all.outsourcing_levels[*].name
+}
@@ -1,3 +1,8 @@
 data "idefix_project" "example" {
   id = 1234
-}
\ No newline at end of file
+}
+
+data "idefix_project" "by_name" {
+  name       = "myproject"
+  company_id = 1234
+}
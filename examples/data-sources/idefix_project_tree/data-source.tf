@@ -0,0 +1,4 @@
+data "idefix_project_tree" "example" {
+  root_id           = 1234
+  include_ancestors = true
+}
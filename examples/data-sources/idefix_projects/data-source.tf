@@ -1,3 +1,10 @@
 data "idefix_projects" "example" {
   name_filter = "myproject"
 }
+
+data "idefix_projects" "company" {
+  company_id       = 1234
+  parent_id        = 5678
+  include_children = true
+  name_regex       = "^prod-"
+}
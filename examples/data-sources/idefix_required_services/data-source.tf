@@ -0,0 +1,5 @@
+data "idefix_required_services" "all" {}
+
+output "required_services" {
+  value = data.idefix_required_services.all.required_services
+}
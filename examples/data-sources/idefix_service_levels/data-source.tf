@@ -0,0 +1,8 @@
+data "idefix_service_levels" "all" {}
+
+resource "idefix_ci" "example" {
+  name             = "myci"
+  company_id       = 1234
+  project_ids      = [1, 2]
+  service_level_id = data.idefix_service_levels.all.by_name["Gold"]
+}
@@ -0,0 +1,61 @@
+package idefix
+
+import (
+	"context"
+	"sync"
+)
+
+type catalogCacheKey struct {
+	client *apiClient
+	name   string
+}
+
+// catalogCacheEntry is a reference list of the cache. Its mutex is held while
+// the list is fetched, so that concurrent plans fetch it once, without
+// blocking the other lists.
+type catalogCacheEntry struct {
+	sync.Mutex
+	items   []catalogItem
+	fetched bool
+}
+
+// catalogCache keeps the reference lists of Idefix for the lifetime of the
+// provider process, so that validating a plan does not fetch the same list
+// for every resource. Its mutex only guards the map.
+var catalogCache = struct {
+	sync.Mutex
+	entries map[catalogCacheKey]*catalogCacheEntry
+}{
+	entries: make(map[catalogCacheKey]*catalogCacheEntry),
+}
+
+// cachedCatalog returns the reference list name, fetching it through list
+// the first time it is requested. A failed fetch is not cached.
+func cachedCatalog(ctx context.Context, client *apiClient, name string, list func(context.Context, *apiClient) ([]catalogItem, error)) ([]catalogItem, error) {
+	key := catalogCacheKey{client: client, name: name}
+
+	catalogCache.Lock()
+	entry, ok := catalogCache.entries[key]
+	if !ok {
+		entry = &catalogCacheEntry{}
+		catalogCache.entries[key] = entry
+	}
+	catalogCache.Unlock()
+
+	entry.Lock()
+	defer entry.Unlock()
+
+	if entry.fetched {
+		return entry.items, nil
+	}
+
+	items, err := list(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.items = items
+	entry.fetched = true
+
+	return items, nil
+}
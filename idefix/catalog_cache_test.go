@@ -0,0 +1,72 @@
+package idefix
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCachedCatalog(t *testing.T) {
+	ctx := context.Background()
+	client := &apiClient{}
+
+	var mu sync.Mutex
+	var fetches int
+	release := make(chan struct{})
+	slow := func(context.Context, *apiClient) ([]catalogItem, error) {
+		mu.Lock()
+		fetches++
+		mu.Unlock()
+
+		<-release
+
+		return []catalogItem{{ID: 1, Name: "slow"}}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if _, err := cachedCatalog(ctx, client, "slow", slow); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	// Fetching another list must not wait for the slow one.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		cachedCatalog(ctx, client, "fast", func(context.Context, *apiClient) ([]catalogItem, error) {
+			return nil, nil
+		})
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("fetching a list waited for the fetch of another one")
+	}
+
+	close(release)
+	wg.Wait()
+
+	if fetches != 1 {
+		t.Errorf("the list was fetched %d times, want 1", fetches)
+	}
+
+	failures := 0
+	failing := func(context.Context, *apiClient) ([]catalogItem, error) {
+		failures++
+		return nil, errors.New("unavailable")
+	}
+	cachedCatalog(ctx, client, "failing", failing)
+	cachedCatalog(ctx, client, "failing", failing)
+	if failures != 2 {
+		t.Errorf("a failed fetch was cached, got %d fetches, want 2", failures)
+	}
+}
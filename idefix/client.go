@@ -0,0 +1,176 @@
+package idefix
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/marty-macfly/goidefix"
+	"github.com/marty-macfly/goidefix/services/authentification"
+	"github.com/marty-macfly/goidefix/services/ci"
+	"github.com/marty-macfly/goidefix/services/company"
+	"github.com/marty-macfly/goidefix/services/equipment"
+	"github.com/marty-macfly/goidefix/services/monitoring"
+	"github.com/marty-macfly/goidefix/services/project"
+)
+
+// The services of the Idefix API used by the provider, as implemented by the
+// goidefix client. The tests reach internal/fakeidefix through goidefix, and
+// wrap the services to inject the answers the fake does not give.
+//
+// Besides the project, CI, equipment and monitoring calls the provider has
+// always made, they include the company service, CI.Search, the List methods
+// of the reference lists and the CompanyID and ParentID filters of
+// project.SearchRequest. The errors of the client must also carry the HTTP
+// status code of Idefix with a StatusCode() int method, which isNotFound
+// relies on. The goidefix version required by go.mod must provide all of it.
+type (
+	projectAPI interface {
+		Create(context.Context, *project.CreateRequest) (*project.CreateResponse, error)
+		Read(context.Context, *project.ReadRequest) (*project.ReadResponse, error)
+		Update(context.Context, *project.UpdateRequest) (*project.UpdateResponse, error)
+		Delete(context.Context, *project.DeleteRequest) (*project.DeleteResponse, error)
+		Search(context.Context, *project.SearchRequest) (*[]project.SearchResponse, error)
+	}
+
+	ciAPI interface {
+		Create(context.Context, *ci.CreateRequest) (*ci.CreateResponse, error)
+		Read(context.Context, *ci.ReadRequest) (*ci.ReadResponse, error)
+		Update(context.Context, *ci.UpdateRequest) (*ci.UpdateResponse, error)
+		Search(context.Context, *ci.SearchRequest) (*[]ci.SearchResponse, error)
+		ReadServiceCloud(context.Context, *ci.ReadServiceCloudRequest) (*ci.ReadServiceCloudResponse, error)
+		UpdateServiceCloud(context.Context, *ci.UpdateServiceCloudRequest) (*ci.UpdateServiceCloudResponse, error)
+		ReadUseAndKeyDate(context.Context, *ci.ReadUseAndKeyDateRequest) (*ci.ReadUseAndKeyDateResponse, error)
+		UpdateUseAndKeyDate(context.Context, *ci.UpdateUseAndKeyDateRequest) (*ci.UpdateUseAndKeyDateResponse, error)
+		UpdatePlatform(context.Context, *ci.UpdatePlatformRequest) (*ci.UpdatePlatformResponse, error)
+		ListTypes(context.Context, *ci.ListTypesRequest) (*[]ci.ListTypesResponse, error)
+		ListServiceLevels(context.Context, *ci.ListServiceLevelsRequest) (*[]ci.ListServiceLevelsResponse, error)
+		ListOutsourcingLevels(context.Context, *ci.ListOutsourcingLevelsRequest) (*[]ci.ListOutsourcingLevelsResponse, error)
+		ListEnvironments(context.Context, *ci.ListEnvironmentsRequest) (*[]ci.ListEnvironmentsResponse, error)
+		ListFunctions(context.Context, *ci.ListFunctionsRequest) (*[]ci.ListFunctionsResponse, error)
+		ListCloudSubscriptions(context.Context, *ci.ListCloudSubscriptionsRequest) (*[]ci.ListCloudSubscriptionsResponse, error)
+		ListCloudProducts(context.Context, *ci.ListCloudProductsRequest) (*[]ci.ListCloudProductsResponse, error)
+		ListCloudRegions(context.Context, *ci.ListCloudRegionsRequest) (*[]ci.ListCloudRegionsResponse, error)
+	}
+
+	equipmentAPI interface {
+		ReadAT(context.Context, *equipment.ReadATRequest) (*equipment.ReadATResponse, error)
+		UpdateAT(context.Context, *equipment.UpdateATRequest) (*equipment.UpdateATResponse, error)
+		Delete(context.Context, *equipment.DeleteRequest) (*equipment.DeleteResponse, error)
+		ListMonitoringTools(context.Context, *equipment.ListMonitoringToolsRequest) (*[]equipment.ListMonitoringToolsResponse, error)
+		ListRequiredServices(context.Context, *equipment.ListRequiredServicesRequest) (*[]equipment.ListRequiredServicesResponse, error)
+	}
+
+	monitoringAPI interface {
+		SearchEvents(context.Context, *monitoring.SearchEventsRequest) (*[]monitoring.SearchEventsResponse, error)
+		DeleteEvents(context.Context, *monitoring.DeleteEventsRequest) (*monitoring.DeleteEventsResponse, error)
+	}
+
+	companyAPI interface {
+		Read(context.Context, *company.ReadRequest) (*company.ReadResponse, error)
+		Search(context.Context, *company.SearchRequest) (*[]company.SearchResponse, error)
+	}
+)
+
+// apiClient is the client of the Idefix API the resources and data sources are
+// configured with.
+type apiClient struct {
+	Project    projectAPI
+	CI         ciAPI
+	Equipment  equipmentAPI
+	Monitoring monitoringAPI
+	Company    companyAPI
+}
+
+// sharedClient logs in Idefix once for the SDK and framework providers, which
+// are served together and configured with the same settings.
+type sharedClient struct {
+	mu       sync.Mutex
+	settings [3]string
+	client   *apiClient
+}
+
+// get returns the client logged in with the given settings, logging in on
+// the first call or when they change.
+func (c *sharedClient) get(ctx context.Context, url string, login string, password string) (*apiClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	settings := [3]string{url, login, password}
+	if c.client != nil && c.settings == settings {
+		return c.client, nil
+	}
+
+	client, err := newClient(ctx, url, login, password)
+	if err != nil {
+		return nil, err
+	}
+	c.settings = settings
+	c.client = client
+
+	return client, nil
+}
+
+// newClient returns a client logged in to Idefix. The tests replace it to
+// count the logins.
+var newClient = newIdefixClient
+
+func newIdefixClient(ctx context.Context, url string, login string, password string) (*apiClient, error) {
+	var client *goidefix.Idefix
+	var err error
+
+	if url == "" {
+		client, err = goidefix.New(ctx)
+	} else {
+		client, err = goidefix.NewWithEndpoint(ctx, url)
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = client.Authentification.Login(ctx, &authentification.LoginRequest{
+		Login:    login,
+		Password: password,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiClient{
+		Project:    client.Project,
+		CI:         client.CI,
+		Equipment:  client.Equipment,
+		Monitoring: client.Monitoring,
+		Company:    client.Company,
+	}, nil
+}
+
+// waitUntilReadable polls read, for up to timeout, until the object Idefix
+// has just created under id exists, that is until read returns it. Only its
+// existence is waited for: Idefix may normalize the other attributes, which
+// must not keep the wait going until the timeout.
+func waitUntilReadable(ctx context.Context, id string, timeout time.Duration, read func() (bool, error)) error {
+	conf := &resource.StateChangeConf{
+		Pending: []string{"pending"},
+		Target:  []string{"available"},
+		Refresh: func() (interface{}, string, error) {
+			found, err := read()
+			if isNotFound(err) || (err == nil && !found) {
+				// A nil result would count as a not found check and end
+				// the wait early, the object is expected to show up.
+				return id, "pending", nil
+			}
+			if err != nil {
+				return nil, "", err
+			}
+
+			return id, "available", nil
+		},
+		Timeout:    timeout,
+		MinTimeout: 2 * time.Second,
+	}
+
+	_, err := conf.WaitForStateContext(ctx)
+
+	return err
+}
@@ -0,0 +1,364 @@
+package idefix
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/marty-macfly/goidefix/services/ci"
+	"github.com/marty-macfly/goidefix/services/equipment"
+)
+
+// catalogItem is an entry of one of the reference lists of Idefix.
+type catalogItem struct {
+	ID          int
+	Name        string
+	Description string
+	CompanyID   int
+}
+
+// catalogLister fetches the entries of a reference list from Idefix.
+type catalogLister func(ctx context.Context, d *schema.ResourceData, client *apiClient) ([]catalogItem, error)
+
+// catalog describes a data source listing one of the reference lists of
+// Idefix, such as the CI types or the service levels.
+type catalog struct {
+	// Name is the name of the data source.
+	Name string
+	// Attribute is the name of the computed list holding the entries.
+	Attribute string
+	// Item is the name of an entry, used in the descriptions.
+	Item string
+	// HasDescription tells whether the entries carry a description.
+	HasDescription bool
+	// HasCompanyID tells whether the entries belong to a company.
+	HasCompanyID bool
+	// Filters are the optional arguments restricting the list.
+	Filters map[string]*schema.Schema
+	// List fetches the entries from Idefix.
+	List catalogLister
+}
+
+// catalogs are the data sources listing the reference lists of Idefix.
+var catalogs = []catalog{
+	{Name: "idefix_ci_types", Attribute: "ci_types", Item: "CI type", List: unfiltered(listCITypes)},
+	{Name: "idefix_service_levels", Attribute: "service_levels", Item: "service level", List: unfiltered(listServiceLevels)},
+	{Name: "idefix_outsourcing_levels", Attribute: "outsourcing_levels", Item: "outsourcing level", List: unfiltered(listOutsourcingLevels)},
+	{Name: "idefix_environments", Attribute: "environments", Item: "environment", HasDescription: true, List: unfiltered(listEnvironments)},
+	{Name: "idefix_functions", Attribute: "functions", Item: "function", HasDescription: true, List: unfiltered(listFunctions)},
+	{Name: "idefix_monitoring_tools", Attribute: "monitoring_tools", Item: "monitoring tool", List: unfiltered(listMonitoringTools)},
+	{Name: "idefix_required_services", Attribute: "required_services", Item: "required service", List: unfiltered(listRequiredServices)},
+	{
+		Name:         "idefix_cloud_subscriptions",
+		Attribute:    "cloud_subscriptions",
+		Item:         "cloud subscription",
+		HasCompanyID: true,
+		Filters: map[string]*schema.Schema{
+			"company_id": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Description: "Company ID to filter the list of cloud subscriptions.",
+			},
+		},
+		List: func(ctx context.Context, d *schema.ResourceData, client *apiClient) ([]catalogItem, error) {
+			return listCloudSubscriptions(ctx, client, d.Get("company_id").(int))
+		},
+	},
+	{Name: "idefix_cloud_products", Attribute: "cloud_products", Item: "cloud product", List: unfiltered(listCloudProducts)},
+	{Name: "idefix_cloud_regions", Attribute: "cloud_regions", Item: "cloud region", List: unfiltered(listCloudRegions)},
+}
+
+// unfiltered returns the lister of a data source without filters, from the
+// function fetching the list, which is also used to validate the CIs.
+func unfiltered(list func(context.Context, *apiClient) ([]catalogItem, error)) catalogLister {
+	return func(ctx context.Context, d *schema.ResourceData, client *apiClient) ([]catalogItem, error) {
+		return list(ctx, client)
+	}
+}
+
+// catalogHasID reports whether one of the items has the given ID.
+func catalogHasID(items []catalogItem, id int) bool {
+	for _, item := range items {
+		if item.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// catalogHasName reports whether one of the items has the given name.
+func catalogHasName(items []catalogItem, name string) bool {
+	for _, item := range items {
+		if item.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func dataSourceCatalog(c catalog) *schema.Resource {
+	item := map[string]*schema.Schema{
+		"id": {
+			Type:        schema.TypeInt,
+			Computed:    true,
+			Description: fmt.Sprintf("The ID of the %s.", c.Item),
+		},
+		"name": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: fmt.Sprintf("The name of the %s.", c.Item),
+		},
+	}
+	if c.HasDescription {
+		item["description"] = &schema.Schema{
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: fmt.Sprintf("The description of the %s.", c.Item),
+		}
+	}
+	if c.HasCompanyID {
+		item["company_id"] = &schema.Schema{
+			Type:        schema.TypeInt,
+			Computed:    true,
+			Description: fmt.Sprintf("The ID of the company of the %s.", c.Item),
+		}
+	}
+
+	s := map[string]*schema.Schema{
+		"id": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The ID of this resource.",
+		},
+		c.Attribute: {
+			Type:        schema.TypeList,
+			Computed:    true,
+			Description: fmt.Sprintf("The %s list.", c.Item),
+			Elem: &schema.Resource{
+				Schema: item,
+			},
+		},
+		"by_name": {
+			Type:        schema.TypeMap,
+			Computed:    true,
+			Description: fmt.Sprintf("The ID of each %s indexed by its name. The names shared by several entries are left out, as they do not tell which one is meant; look them up in `%s` instead.", c.Item, c.Attribute),
+			Elem: &schema.Schema{
+				Type: schema.TypeInt,
+			},
+		},
+	}
+	for k, v := range c.Filters {
+		s[k] = v
+	}
+
+	return &schema.Resource{
+		ReadContext: c.read,
+		Description: fmt.Sprintf("Use this data source to list the %ss available in Idefix.", c.Item),
+		Schema:      s,
+	}
+}
+
+func (c catalog) read(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	client := m.(*apiClient)
+	items, err := c.List(ctx, d, client)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	names := make(map[string]int, len(items))
+	for _, item := range items {
+		names[item.Name]++
+	}
+
+	list := make([]interface{}, len(items))
+	byName := make(map[string]interface{}, len(items))
+	for i, item := range items {
+		v := map[string]interface{}{
+			"id":   item.ID,
+			"name": item.Name,
+		}
+		if c.HasDescription {
+			v["description"] = item.Description
+		}
+		if c.HasCompanyID {
+			v["company_id"] = item.CompanyID
+		}
+
+		list[i] = v
+
+		// by_name cannot tell apart entries sharing a name.
+		if names[item.Name] == 1 {
+			byName[item.Name] = item.ID
+		}
+	}
+
+	if err := d.Set(c.Attribute, list); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("by_name", byName); err != nil {
+		return diag.FromErr(err)
+	}
+
+	filters := make([]string, 0, len(c.Filters))
+	for k := range c.Filters {
+		filters = append(filters, k)
+	}
+	sort.Strings(filters)
+
+	id := c.Attribute
+	for _, k := range filters {
+		id += fmt.Sprintf("|%s=%v", k, d.Get(k))
+	}
+	d.SetId(strconv.Itoa(schema.HashString(id)))
+
+	return diags
+}
+
+func listCITypes(ctx context.Context, client *apiClient) ([]catalogItem, error) {
+	resp, err := client.CI.ListTypes(ctx, &ci.ListTypesRequest{})
+	if err != nil || resp == nil {
+		return nil, err
+	}
+
+	items := make([]catalogItem, len(*resp))
+	for i, t := range *resp {
+		items[i] = catalogItem{ID: t.ID, Name: t.Name}
+	}
+
+	return items, nil
+}
+
+func listServiceLevels(ctx context.Context, client *apiClient) ([]catalogItem, error) {
+	resp, err := client.CI.ListServiceLevels(ctx, &ci.ListServiceLevelsRequest{})
+	if err != nil || resp == nil {
+		return nil, err
+	}
+
+	items := make([]catalogItem, len(*resp))
+	for i, l := range *resp {
+		items[i] = catalogItem{ID: l.ID, Name: l.Name}
+	}
+
+	return items, nil
+}
+
+func listOutsourcingLevels(ctx context.Context, client *apiClient) ([]catalogItem, error) {
+	resp, err := client.CI.ListOutsourcingLevels(ctx, &ci.ListOutsourcingLevelsRequest{})
+	if err != nil || resp == nil {
+		return nil, err
+	}
+
+	items := make([]catalogItem, len(*resp))
+	for i, l := range *resp {
+		items[i] = catalogItem{ID: l.ID, Name: l.Name}
+	}
+
+	return items, nil
+}
+
+func listEnvironments(ctx context.Context, client *apiClient) ([]catalogItem, error) {
+	resp, err := client.CI.ListEnvironments(ctx, &ci.ListEnvironmentsRequest{})
+	if err != nil || resp == nil {
+		return nil, err
+	}
+
+	items := make([]catalogItem, len(*resp))
+	for i, e := range *resp {
+		items[i] = catalogItem{ID: e.ID, Name: e.Name, Description: e.Description}
+	}
+
+	return items, nil
+}
+
+func listFunctions(ctx context.Context, client *apiClient) ([]catalogItem, error) {
+	resp, err := client.CI.ListFunctions(ctx, &ci.ListFunctionsRequest{})
+	if err != nil || resp == nil {
+		return nil, err
+	}
+
+	items := make([]catalogItem, len(*resp))
+	for i, f := range *resp {
+		items[i] = catalogItem{ID: f.ID, Name: f.Name, Description: f.Description}
+	}
+
+	return items, nil
+}
+
+func listMonitoringTools(ctx context.Context, client *apiClient) ([]catalogItem, error) {
+	resp, err := client.Equipment.ListMonitoringTools(ctx, &equipment.ListMonitoringToolsRequest{})
+	if err != nil || resp == nil {
+		return nil, err
+	}
+
+	items := make([]catalogItem, len(*resp))
+	for i, t := range *resp {
+		items[i] = catalogItem{ID: t.ID, Name: t.Name}
+	}
+
+	return items, nil
+}
+
+func listRequiredServices(ctx context.Context, client *apiClient) ([]catalogItem, error) {
+	resp, err := client.Equipment.ListRequiredServices(ctx, &equipment.ListRequiredServicesRequest{})
+	if err != nil || resp == nil {
+		return nil, err
+	}
+
+	items := make([]catalogItem, len(*resp))
+	for i, s := range *resp {
+		items[i] = catalogItem{ID: s.ID, Name: s.Name}
+	}
+
+	return items, nil
+}
+
+func listCloudSubscriptions(ctx context.Context, client *apiClient, companyID int) ([]catalogItem, error) {
+	resp, err := client.CI.ListCloudSubscriptions(ctx, &ci.ListCloudSubscriptionsRequest{
+		CompanyID: companyID,
+	})
+	if err != nil || resp == nil {
+		return nil, err
+	}
+
+	items := make([]catalogItem, len(*resp))
+	for i, s := range *resp {
+		items[i] = catalogItem{ID: s.ID, Name: s.Name, CompanyID: s.CompanyID}
+	}
+
+	return items, nil
+}
+
+func listCloudProducts(ctx context.Context, client *apiClient) ([]catalogItem, error) {
+	resp, err := client.CI.ListCloudProducts(ctx, &ci.ListCloudProductsRequest{})
+	if err != nil || resp == nil {
+		return nil, err
+	}
+
+	items := make([]catalogItem, len(*resp))
+	for i, p := range *resp {
+		items[i] = catalogItem{ID: p.ID, Name: p.Name}
+	}
+
+	return items, nil
+}
+
+func listCloudRegions(ctx context.Context, client *apiClient) ([]catalogItem, error) {
+	resp, err := client.CI.ListCloudRegions(ctx, &ci.ListCloudRegionsRequest{})
+	if err != nil || resp == nil {
+		return nil, err
+	}
+
+	items := make([]catalogItem, len(*resp))
+	for i, r := range *resp {
+		items[i] = catalogItem{ID: r.ID, Name: r.Name}
+	}
+
+	return items, nil
+}
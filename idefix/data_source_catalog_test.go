@@ -0,0 +1,117 @@
+package idefix
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/marty-macfly/goidefix/services/ci"
+	"github.com/marty-macfly/terraform-provider-idefix/internal/fakeidefix"
+)
+
+func TestAccDataSourceCatalogs_basic(t *testing.T) {
+	catalogs := []struct {
+		dataSource string
+		attribute  string
+		byCompany  bool
+		count      int
+		name       string
+		id         int
+	}{
+		{"idefix_ci_types", "ci_types", false, len(fakeidefix.CITypes), fakeidefix.CITypes[0].Name, fakeidefix.CITypes[0].ID},
+		{"idefix_service_levels", "service_levels", false, len(fakeidefix.ServiceLevels), fakeidefix.ServiceLevels[0].Name, fakeidefix.ServiceLevels[0].ID},
+		{"idefix_outsourcing_levels", "outsourcing_levels", false, len(fakeidefix.OutsourcingLevels), fakeidefix.OutsourcingLevels[1].Name, fakeidefix.OutsourcingLevels[1].ID},
+		{"idefix_environments", "environments", false, len(fakeidefix.Environments), fakeidefix.Environments[0].Name, fakeidefix.Environments[0].ID},
+		{"idefix_functions", "functions", false, len(fakeidefix.Functions), fakeidefix.Functions[0].Name, fakeidefix.Functions[0].ID},
+		{"idefix_monitoring_tools", "monitoring_tools", false, len(fakeidefix.MonitoringTools), fakeidefix.MonitoringTools[0].Name, fakeidefix.MonitoringTools[0].ID},
+		{"idefix_required_services", "required_services", false, len(fakeidefix.RequiredServices), fakeidefix.RequiredServices[0].Name, fakeidefix.RequiredServices[0].ID},
+		{"idefix_cloud_subscriptions", "cloud_subscriptions", true, 1, fakeidefix.CloudSubscriptions[0].Name, fakeidefix.CloudSubscriptions[0].ID},
+		{"idefix_cloud_products", "cloud_products", false, len(fakeidefix.CloudProducts), fakeidefix.CloudProducts[0].Name, fakeidefix.CloudProducts[0].ID},
+		{"idefix_cloud_regions", "cloud_regions", false, len(fakeidefix.CloudRegions), fakeidefix.CloudRegions[0].Name, fakeidefix.CloudRegions[0].ID},
+	}
+
+	for _, c := range catalogs {
+		c := c
+
+		t.Run(c.dataSource, func(t *testing.T) {
+			api := testAccIdefix(t)
+			name := "data." + c.dataSource + ".test"
+
+			var config string
+			if c.byCompany {
+				config = fmt.Sprintf("company_id = %d", api.CompanyID)
+			}
+
+			// The content of the catalogs is only known on the fake.
+			check := resource.ComposeTestCheckFunc(
+				resource.TestMatchResourceAttr(name, c.attribute+".#", regexp.MustCompile("^[1-9]")),
+				resource.TestMatchResourceAttr(name, "by_name.%", regexp.MustCompile("^[1-9]")),
+			)
+			if api.Fake != nil {
+				check = resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(name, c.attribute+".#", strconv.Itoa(c.count)),
+					resource.TestCheckResourceAttr(name, "by_name."+c.name, strconv.Itoa(c.id)),
+				)
+			}
+			if c.byCompany {
+				check = resource.ComposeTestCheckFunc(
+					check,
+					resource.TestCheckResourceAttr(name, c.attribute+".0.company_id", strconv.Itoa(api.CompanyID)),
+				)
+			}
+
+			resource.Test(t, resource.TestCase{
+				ProtoV5ProviderFactories: testAccProtoV5ProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config: testAccConfig(api, fmt.Sprintf(`
+data %q "test" {
+  %s
+}
+`, c.dataSource, config)),
+						Check: check,
+					},
+				},
+			})
+		})
+	}
+}
+
+func TestDataSourceCatalogRead_duplicateNames(t *testing.T) {
+	api := testFakeIdefix(t)
+
+	types := fakeidefix.CITypes
+	fakeidefix.CITypes = append(append([]ci.ListTypesResponse{}, types...), ci.ListTypesResponse{ID: 43, Name: types[0].Name})
+	t.Cleanup(func() {
+		fakeidefix.CITypes = types
+	})
+
+	var c catalog
+	for _, c = range catalogs {
+		if c.Name == "idefix_ci_types" {
+			break
+		}
+	}
+
+	r := dataSourceCatalog(c)
+	d := schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{})
+	if diags := r.ReadContext(context.Background(), d, api.Client); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	if got := len(d.Get(c.Attribute).([]interface{})); got != len(fakeidefix.CITypes) {
+		t.Errorf("got %d %s, want %d", got, c.Attribute, len(fakeidefix.CITypes))
+	}
+
+	byName := d.Get("by_name").(map[string]interface{})
+	if id, ok := byName[types[0].Name]; ok {
+		t.Errorf("by_name has the duplicated name %q, with ID %v", types[0].Name, id)
+	}
+	if id := byName[types[1].Name]; id != types[1].ID {
+		t.Errorf("got by_name[%q] %v, want %d", types[1].Name, id, types[1].ID)
+	}
+}
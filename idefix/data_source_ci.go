@@ -2,122 +2,340 @@ package idefix
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"strings"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/marty-macfly/goidefix"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/marty-macfly/goidefix/services/ci"
+	"github.com/marty-macfly/goidefix/services/equipment"
 )
 
-func dataSourceCI() *schema.Resource {
-	return &schema.Resource{
-		ReadContext: dataSourceCIRead,
+var (
+	_ datasource.DataSourceWithConfigure      = &ciDataSource{}
+	_ datasource.DataSourceWithValidateConfig = &ciDataSource{}
+)
+
+type ciDataSource struct {
+	client *apiClient
+}
+
+type ciDataSourceModel struct {
+	ID              types.String                `tfsdk:"id"`
+	Name            types.String                `tfsdk:"name"`
+	ProjectID       types.Int64                 `tfsdk:"project_id"`
+	TypeID          types.Int64                 `tfsdk:"type_id"`
+	CompanyID       types.Int64                 `tfsdk:"company_id"`
+	ProjectIDs      types.Set                   `tfsdk:"project_ids"`
+	OutsourcingName types.String                `tfsdk:"outsourcing_name"`
+	ServiceLevelID  types.Int64                 `tfsdk:"service_level_id"`
+	Team            types.String                `tfsdk:"team"`
+	IsOwnerLBN      types.Bool                  `tfsdk:"is_owner_lbn"`
+	Comment         types.String                `tfsdk:"comment"`
+	ServiceAT       []ciServiceATModel          `tfsdk:"service_at"`
+	KeyDates        []ciDataSourceKeyDatesModel `tfsdk:"key_dates"`
+	ServiceCloud    []ciServiceCloudModel       `tfsdk:"service_cloud"`
+}
+
+// ciDataSourceKeyDatesModel is a key_dates block of the data source, which
+// gives the environments and functions by ID only.
+type ciDataSourceKeyDatesModel struct {
+	EnvironmentIDs types.Set `tfsdk:"environment_ids"`
+	FunctionIDs    types.Set `tfsdk:"function_ids"`
+}
+
+func newCIDataSource() datasource.DataSource {
+	return &ciDataSource{}
+}
+
+func (r *ciDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_ci"
+}
+
+func (r *ciDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
 		Description: "Use this data source to access information about an existing CI.",
-		Schema: map[string]*schema.Schema{
-			"id": {
-				Type:        schema.TypeString,
-				Required:    true,
-				Description: "The ID of this resource.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "The ID of this resource. Either `id` or `name` must be set.",
 			},
-			"name": {
-				Type:        schema.TypeString,
+			"name": schema.StringAttribute{
+				Optional:    true,
 				Computed:    true,
-				Description: "The name of this CI.",
+				Description: "The name of this CI. Either `id` or `name` must be set.",
 			},
-			"type_id": {
-				Type:        schema.TypeInt,
+			"project_id": schema.Int64Attribute{
+				Optional:    true,
+				Description: "The ID of a project the CI must belong to when it is looked up by `name`.",
+			},
+			"type_id": schema.Int64Attribute{
 				Computed:    true,
 				Description: "The type of the CI.",
 			},
-			"company_id": {
-				Type:        schema.TypeInt,
+			"company_id": schema.Int64Attribute{
+				Optional:    true,
 				Computed:    true,
-				Description: "The company ID associated to the CI.",
+				Description: "The company ID associated to the CI. It restricts the lookup when the CI is looked up by `name`.",
 			},
-			"project_ids": {
-				Type:        schema.TypeList,
+			"project_ids": schema.SetAttribute{
 				Computed:    true,
+				ElementType: types.Int64Type,
 				Description: "The projects associated to the CI.",
-				Elem: &schema.Schema{
-					Type: schema.TypeInt,
-				},
 			},
-			"outsourcing_name": {
-				Type:        schema.TypeString,
+			"outsourcing_name": schema.StringAttribute{
 				Computed:    true,
 				Description: "The Outsourcing level name.",
 			},
-			"service_level_id": {
-				Type:        schema.TypeInt,
+			"service_level_id": schema.Int64Attribute{
 				Computed:    true,
 				Description: "The Level of the service.",
 			},
-			"team": {
-				Type:        schema.TypeString,
+			"team": schema.StringAttribute{
 				Computed:    true,
 				Description: "The team in charge.",
 			},
-			"is_owner_lbn": {
-				Type:        schema.TypeBool,
+			"is_owner_lbn": schema.BoolAttribute{
 				Computed:    true,
 				Description: "The owner of the CI.",
 			},
-			"comment": {
-				Type:        schema.TypeString,
+			"comment": schema.StringAttribute{
 				Computed:    true,
 				Description: "Comment.",
 			},
+			// Nested attributes need the protocol version 6, the provider
+			// is served with the version 5.
+			"service_at": schema.ListAttribute{
+				Computed:    true,
+				Description: "Services AT, with the `required_services` and `monitoring_tool` IDs.",
+				ElementType: ciServiceATType,
+			},
+			"key_dates": schema.ListAttribute{
+				Computed:    true,
+				Description: "Use And Key Date, with the `environment_ids` and `function_ids` of the CI.",
+				ElementType: types.ObjectType{
+					AttrTypes: map[string]attr.Type{
+						"environment_ids": types.SetType{ElemType: types.Int64Type},
+						"function_ids":    types.SetType{ElemType: types.Int64Type},
+					},
+				},
+			},
+			"service_cloud": schema.ListAttribute{
+				Computed:    true,
+				Description: "Service Cloud, with the `subscription_id`, `product_id` and `region_id` of the CI.",
+				ElementType: ciServiceCloudType,
+			},
 		},
 	}
 }
 
-func dataSourceCIRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	var diags diag.Diagnostics
+func (r *ciDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*apiClient)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected data source configure type", fmt.Sprintf("Expected *apiClient, got: %T.", req.ProviderData))
+		return
+	}
+
+	r.client = client
+}
+
+func (r *ciDataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	var config ciDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if config.ID.IsUnknown() || config.Name.IsUnknown() {
+		return
+	}
+
+	if config.ID.IsNull() == config.Name.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("id"), "Invalid attribute combination", "Exactly one of `id` or `name` must be set.")
+	}
+}
+
+func (r *ciDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data ciDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	id := data.ID.ValueString()
+	if data.ID.IsNull() {
+		var diags diag.Diagnostics
 
-	client := m.(*goidefix.Idefix)
-	ci, err := client.CI.Read(ctx, &ci.ReadRequest{
-		ID: d.Get("id").(string),
+		id, diags = dataSourceCISearch(ctx, data.Name.ValueString(), int(data.CompanyID.ValueInt64()), int(data.ProjectID.ValueInt64()), r.client)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	cir, err := r.client.CI.Read(ctx, &ci.ReadRequest{
+		ID: id,
 	})
+	if isNotFound(err) || (err == nil && cir == nil) {
+		resp.Diagnostics.AddAttributeError(path.Root("id"), "CI not found", fmt.Sprintf("No CI with ID %s exists in Idefix.", id))
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("id"), "Unable to read CI", err.Error())
+		return
+	}
+
+	projectIDs, err := splitIDs(cir.ProjectIDs)
 	if err != nil {
-		diag.FromErr(err)
+		resp.Diagnostics.AddAttributeError(path.Root("project_ids"), "Unable to parse the project IDs of the CI", err.Error())
+		return
 	}
 
-	if ci == nil {
-		return diags
+	typeID, err := strconv.Atoi(cir.TypeID)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("type_id"), "Unable to parse the type of the CI", err.Error())
+		return
 	}
 
-	var projectIDs []int
-	pids := strings.Split(ci.ProjectIDs, ",")
-	for _, pid := range pids {
-		if pid != "" {
-			id, err := strconv.Atoi(pid)
-			if err != nil {
-				return diag.FromErr(err)
-			}
+	data.ID = types.StringValue(id)
+	data.Name = types.StringValue(cir.Name)
+	data.CompanyID = types.Int64Value(int64(cir.CompanyID))
+	data.TypeID = types.Int64Value(int64(typeID))
+	data.ProjectIDs = int64Set(projectIDs)
+	data.OutsourcingName = types.StringValue(cir.OutSourcingName)
+	data.ServiceLevelID = types.Int64Value(int64(cir.ServiceLevelID))
+	data.Team = types.StringValue(cir.Team)
+	data.IsOwnerLBN = types.BoolValue(cir.IsOwnerLBN)
+	data.Comment = types.StringValue(cir.Comment)
 
-			projectIDs = append(projectIDs, id)
+	sc, err := r.client.CI.ReadServiceCloud(ctx, &ci.ReadServiceCloudRequest{
+		ID: id,
+	})
+	if isNotFound(err) {
+		sc, err = nil, nil
+	}
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("service_cloud"), "Unable to read the service cloud of the CI", err.Error())
+		return
+	}
+	data.ServiceCloud = make([]ciServiceCloudModel, 0)
+	if sc != nil {
+		serviceCloud, err := flattenServiceCloud(sc)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("service_cloud"), "Unable to parse the service cloud of the CI", err.Error())
+			return
 		}
+		data.ServiceCloud = append(data.ServiceCloud, serviceCloud)
 	}
 
-	typeID, err := strconv.Atoi(ci.TypeID)
+	kd, err := r.client.CI.ReadUseAndKeyDate(ctx, &ci.ReadUseAndKeyDateRequest{
+		ID: id,
+	})
+	if isNotFound(err) {
+		kd, err = nil, nil
+	}
 	if err != nil {
-		diag.FromErr(err)
+		resp.Diagnostics.AddAttributeError(path.Root("key_dates"), "Unable to read the key dates of the CI", err.Error())
+		return
+	}
+	data.KeyDates = make([]ciDataSourceKeyDatesModel, 0)
+	if kd != nil {
+		keyDates, err := flattenKeyDates(kd)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("key_dates"), "Unable to parse the key dates of the CI", err.Error())
+			return
+		}
+		data.KeyDates = append(data.KeyDates, ciDataSourceKeyDatesModel{
+			EnvironmentIDs: keyDates.EnvironmentIDs,
+			FunctionIDs:    keyDates.FunctionIDs,
+		})
 	}
 
-	d.Set("name", ci.Name)
-	d.Set("company_id", ci.CompanyID)
-	d.Set("type_id", typeID)
-	d.Set("company_id", ci.CompanyID)
-	d.Set("project_ids", projectIDs)
-	d.Set("outsourcing_name", ci.OutSourcingName)
-	d.Set("service_level_id", ci.ServiceLevelID)
-	d.Set("team", ci.Team)
-	d.Set("is_owner_lbn", ci.IsOwnerLBN)
-	d.Set("comment", ci.Comment)
+	at, err := r.client.Equipment.ReadAT(ctx, &equipment.ReadATRequest{
+		ID: id,
+	})
+	if isNotFound(err) {
+		at, err = nil, nil
+	}
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("service_at"), "Unable to read the services AT of the CI", err.Error())
+		return
+	}
+	data.ServiceAT = make([]ciServiceATModel, 0)
+	if at != nil {
+		serviceAT, err := flattenServiceAT(at)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("service_at"), "Unable to parse the services AT of the CI", err.Error())
+			return
+		}
+		data.ServiceAT = append(data.ServiceAT, serviceAT)
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
 
-	d.SetId(d.Get("id").(string))
+// dataSourceCISearch resolves the ID of the CI matching exactly the name, and
+// optionally the company and project, given in the configuration.
+func dataSourceCISearch(ctx context.Context, name string, companyID int, projectID int, client *apiClient) (string, diag.Diagnostics) {
+	var diags diag.Diagnostics
 
-	return diags
+	resp, err := client.CI.Search(ctx, &ci.SearchRequest{
+		Name:      name,
+		CompanyID: companyID,
+		ProjectID: projectID,
+	})
+	if isNotFound(err) {
+		resp, err = nil, nil
+	}
+	if err != nil {
+		diags.AddAttributeError(path.Root("name"), "Unable to search CI", err.Error())
+		return "", diags
+	}
+
+	// The filters are checked again client-side, so that a filter ignored by
+	// Idefix cannot return a CI of another company or project.
+	var ids []string
+	if resp != nil {
+		for _, c := range *resp {
+			if c.Name != name || (companyID != 0 && c.CompanyID != companyID) {
+				continue
+			}
+
+			if projectID != 0 {
+				projectIDs, err := splitIDs(c.ProjectIDs)
+				if err != nil {
+					diags.AddAttributeError(path.Root("project_id"), "Unable to parse the project IDs of a CI", err.Error())
+					return "", diags
+				}
+				if !containsInt(projectIDs, projectID) {
+					continue
+				}
+			}
+
+			ids = append(ids, c.ID)
+		}
+	}
+
+	switch len(ids) {
+	case 0:
+		diags.AddAttributeError(path.Root("name"), "CI not found", fmt.Sprintf("No CI named %q exists in Idefix.", name))
+		return "", diags
+	case 1:
+		return ids[0], nil
+	default:
+		diags.AddAttributeError(path.Root("name"), "Multiple CIs found", fmt.Sprintf("%d CIs named %q exist in Idefix (IDs: %s), set company_id or project_id to narrow the search.", len(ids), name, strings.Join(ids, ", ")))
+		return "", diags
+	}
 }
@@ -0,0 +1,84 @@
+package idefix
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/marty-macfly/goidefix/services/ci"
+)
+
+func TestAccDataSourceCI_basic(t *testing.T) {
+	api := testAccIdefix(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV5ProviderFactories: testAccProtoV5ProviderFactories,
+		CheckDestroy:             testAccCheckCIDestroy(api),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(api, testAccResourceCIConfig(api, "tf-acc-ci", "Unix")+`
+data "idefix_ci" "by_id" {
+  id = idefix_ci.test.id
+}
+
+data "idefix_ci" "by_name" {
+  name       = idefix_ci.test.name
+  company_id = idefix_ci.test.company_id
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrPair("data.idefix_ci.by_id", "name", "idefix_ci.test", "name"),
+					resource.TestCheckResourceAttrPair("data.idefix_ci.by_id", "team", "idefix_ci.test", "team"),
+					resource.TestCheckResourceAttr("data.idefix_ci.by_id", "project_ids.#", "2"),
+					resource.TestCheckResourceAttr("data.idefix_ci.by_id", "service_cloud.0.region_id", "1"),
+					resource.TestCheckResourceAttr("data.idefix_ci.by_id", "key_dates.0.environment_ids.#", "2"),
+					resource.TestCheckResourceAttr("data.idefix_ci.by_id", "service_at.0.required_services.#", "2"),
+					resource.TestCheckResourceAttrPair("data.idefix_ci.by_name", "id", "idefix_ci.test", "id"),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSourceCIRead_search(t *testing.T) {
+	api := testFakeIdefix(t)
+	client := *api.Client
+	client.CI = staticSearchCIAPI{client.CI, []ci.SearchResponse{
+		{ID: "1", Name: "web-01", CompanyID: api.CompanyID, ProjectIDs: "1"},
+		{ID: "2", Name: "web-01", CompanyID: api.CompanyID, ProjectIDs: "2,3"},
+		{ID: "3", Name: "web-01", CompanyID: api.CompanyID + 1, ProjectIDs: "1"},
+		{ID: "4", Name: "web-010", CompanyID: api.CompanyID, ProjectIDs: "1"},
+	}}
+	s := testProviderServer(t, &client)
+
+	for _, c := range []struct {
+		config  map[string]interface{}
+		summary string
+	}{
+		{map[string]interface{}{"name": "web-01"}, "Multiple CIs found"},
+		{map[string]interface{}{"name": "web-01", "company_id": api.CompanyID}, "Multiple CIs found"},
+		{map[string]interface{}{"name": "web-01", "company_id": api.CompanyID + 2}, "CI not found"},
+		{map[string]interface{}{"name": "web-01", "project_id": 4}, "CI not found"},
+	} {
+		_, diags := s.readDataSource("idefix_ci", c.config)
+		if len(diags) != 1 || diags[0].Summary != c.summary || !diags[0].Attribute.Equal(testAttributePath("name")) {
+			t.Errorf("%v: got %s, want %q on name", c.config, testDiagnostics(diags), c.summary)
+		}
+	}
+
+	for _, c := range []struct {
+		name      string
+		companyID int
+		projectID int
+		want      string
+	}{
+		{"web-01", api.CompanyID + 1, 0, "3"},
+		{"web-01", api.CompanyID, 1, "1"},
+		{"web-01", 0, 3, "2"},
+	} {
+		id, diags := dataSourceCISearch(context.Background(), c.name, c.companyID, c.projectID, &client)
+		if diags.HasError() || id != c.want {
+			t.Errorf("%+v: got %q (%v), want %q", c, id, diags, c.want)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package idefix
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	sdkschema "github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/marty-macfly/goidefix/services/ci"
+)
+
+var _ datasource.DataSourceWithConfigure = &cisDataSource{}
+
+type cisDataSource struct {
+	client *apiClient
+}
+
+type cisDataSourceModel struct {
+	ID             types.String           `tfsdk:"id"`
+	NameFilter     types.String           `tfsdk:"name_filter"`
+	NameRegex      types.String           `tfsdk:"name_regex"`
+	CompanyID      types.Int64            `tfsdk:"company_id"`
+	ProjectID      types.Int64            `tfsdk:"project_id"`
+	TypeID         types.Int64            `tfsdk:"type_id"`
+	Team           types.String           `tfsdk:"team"`
+	ServiceLevelID types.Int64            `tfsdk:"service_level_id"`
+	CIs            []cisDataSourceCIModel `tfsdk:"cis"`
+}
+
+type cisDataSourceCIModel struct {
+	ID         types.String `tfsdk:"id"`
+	Name       types.String `tfsdk:"name"`
+	TypeID     types.Int64  `tfsdk:"type_id"`
+	CompanyID  types.Int64  `tfsdk:"company_id"`
+	ProjectIDs types.Set    `tfsdk:"project_ids"`
+}
+
+func newCIsDataSource() datasource.DataSource {
+	return &cisDataSource{}
+}
+
+func (r *cisDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_cis"
+}
+
+func (r *cisDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Use this data source to access information about existing CIs.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "The ID of this resource.",
+			},
+			"name_filter": schema.StringAttribute{
+				Optional:    true,
+				Description: "Text the name of the CIs must contain, compared case-insensitively.",
+			},
+			"name_regex": schema.StringAttribute{
+				Optional:    true,
+				Validators:  []validator.String{regexpValidator{}},
+				Description: "Regular expression the name of the CIs must match.",
+			},
+			"company_id": schema.Int64Attribute{
+				Optional:    true,
+				Description: "Company ID to filter the list of CIs.",
+			},
+			"project_id": schema.Int64Attribute{
+				Optional:    true,
+				Description: "Project ID to filter the list of CIs.",
+			},
+			"type_id": schema.Int64Attribute{
+				Optional:    true,
+				Description: "Type to filter the list of CIs.",
+			},
+			"team": schema.StringAttribute{
+				Optional:    true,
+				Description: "Team in charge to filter the list of CIs.",
+			},
+			"service_level_id": schema.Int64Attribute{
+				Optional:    true,
+				Description: "Level of the service to filter the list of CIs.",
+			},
+			// Nested attributes need the protocol version 6, the provider
+			// is served with the version 5.
+			"cis": schema.ListAttribute{
+				Computed:    true,
+				Description: "The CIs list, with the `id`, `name`, `type_id`, `company_id` and `project_ids` of each CI.",
+				ElementType: types.ObjectType{
+					AttrTypes: map[string]attr.Type{
+						"id":          types.StringType,
+						"name":        types.StringType,
+						"type_id":     types.Int64Type,
+						"company_id":  types.Int64Type,
+						"project_ids": types.SetType{ElemType: types.Int64Type},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (r *cisDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*apiClient)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected data source configure type", fmt.Sprintf("Expected *apiClient, got: %T.", req.ProviderData))
+		return
+	}
+
+	r.client = client
+}
+
+func (r *cisDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data cisDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	name := data.NameFilter.ValueString()
+	nameRegex := data.NameRegex.ValueString()
+
+	search := &ci.SearchRequest{
+		Name:           name,
+		CompanyID:      int(data.CompanyID.ValueInt64()),
+		ProjectID:      int(data.ProjectID.ValueInt64()),
+		TypeID:         int(data.TypeID.ValueInt64()),
+		Team:           data.Team.ValueString(),
+		ServiceLevelID: int(data.ServiceLevelID.ValueInt64()),
+	}
+
+	cis, err := r.client.CI.Search(ctx, search)
+	if isNotFound(err) {
+		cis, err = nil, nil
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to search the CIs", err.Error())
+		return
+	}
+
+	var re *regexp.Regexp
+	if nameRegex != "" {
+		re = regexp.MustCompile(nameRegex)
+	}
+
+	// The names are matched again client-side, as the way Idefix matches
+	// them is not documented.
+	var filtered []ci.SearchResponse
+	if cis != nil {
+		for _, c := range *cis {
+			if !strings.Contains(strings.ToLower(c.Name), strings.ToLower(name)) {
+				continue
+			}
+
+			if re != nil && !re.MatchString(c.Name) {
+				continue
+			}
+
+			filtered = append(filtered, c)
+		}
+	}
+
+	data.CIs, err = flattenCIsData(&filtered)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to parse the CIs", err.Error())
+		return
+	}
+
+	data.ID = types.StringValue(strconv.Itoa(sdkschema.HashString(fmt.Sprintf("%+v|%s", *search, nameRegex))))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func flattenCIsData(cis *[]ci.SearchResponse) ([]cisDataSourceCIModel, error) {
+	cs := make([]cisDataSourceCIModel, 0)
+
+	if cis != nil {
+		for _, c := range *cis {
+			typeID, err := strconv.Atoi(c.TypeID)
+			if err != nil {
+				return nil, err
+			}
+
+			projectIDs, err := splitIDs(c.ProjectIDs)
+			if err != nil {
+				return nil, err
+			}
+
+			cs = append(cs, cisDataSourceCIModel{
+				ID:         types.StringValue(c.ID),
+				Name:       types.StringValue(c.Name),
+				TypeID:     types.Int64Value(int64(typeID)),
+				CompanyID:  types.Int64Value(int64(c.CompanyID)),
+				ProjectIDs: int64Set(projectIDs),
+			})
+		}
+	}
+
+	return cs, nil
+}
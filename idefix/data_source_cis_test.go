@@ -0,0 +1,94 @@
+package idefix
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/marty-macfly/goidefix/services/ci"
+)
+
+func TestAccDataSourceCIs_basic(t *testing.T) {
+	api := testAccIdefix(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV5ProviderFactories: testAccProtoV5ProviderFactories,
+		CheckDestroy:             testAccCheckCIDestroy(api),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(api, testAccResourceCIConfig(api, "tf-acc-ci", "Unix")+`
+data "idefix_cis" "by_name" {
+  name_filter = "tf-acc"
+
+  depends_on = [idefix_ci.test]
+}
+
+data "idefix_cis" "by_project" {
+  project_id = idefix_project.test[0].id
+
+  depends_on = [idefix_ci.test]
+}
+
+data "idefix_cis" "by_team" {
+  team = "Windows"
+
+  depends_on = [idefix_ci.test]
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.idefix_cis.by_name", "cis.#", "1"),
+					resource.TestCheckResourceAttrPair("data.idefix_cis.by_name", "cis.0.id", "idefix_ci.test", "id"),
+					resource.TestCheckResourceAttr("data.idefix_cis.by_project", "cis.#", "1"),
+					resource.TestCheckResourceAttr("data.idefix_cis.by_team", "cis.#", "0"),
+				),
+			},
+		},
+	})
+}
+
+// staticSearchCIAPI answers every search of CIs with cis, whatever its
+// filters.
+type staticSearchCIAPI struct {
+	ciAPI
+	cis []ci.SearchResponse
+}
+
+func (c staticSearchCIAPI) Search(ctx context.Context, req *ci.SearchRequest) (*[]ci.SearchResponse, error) {
+	cis := append([]ci.SearchResponse{}, c.cis...)
+
+	return &cis, nil
+}
+
+func TestDataSourceCIsRead_names(t *testing.T) {
+	client := *testFakeIdefix(t).Client
+	client.CI = staticSearchCIAPI{client.CI, []ci.SearchResponse{
+		{ID: "1", Name: "web-01", TypeID: "41", ProjectIDs: "1"},
+		{ID: "2", Name: "WEB-02", TypeID: "41", ProjectIDs: "1"},
+		{ID: "3", Name: "db-01", TypeID: "41", ProjectIDs: "1"},
+	}}
+	s := testProviderServer(t, &client)
+
+	for _, c := range []struct {
+		config map[string]interface{}
+		want   []string
+	}{
+		{map[string]interface{}{}, []string{"1", "2", "3"}},
+		{map[string]interface{}{"name_filter": "Web"}, []string{"1", "2"}},
+		{map[string]interface{}{"name_regex": "-01$"}, []string{"1", "3"}},
+		{map[string]interface{}{"name_filter": "web", "name_regex": "^[a-z]"}, []string{"1"}},
+	} {
+		state, diags := s.readDataSource("idefix_cis", c.config)
+		if testHasError(diags) {
+			t.Fatal(testDiagnostics(diags))
+		}
+
+		got := make([]string, 0)
+		for _, v := range testAttrs(state)["cis"].([]interface{}) {
+			got = append(got, v.(map[string]interface{})["id"].(string))
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%v: got %v, want %v", c.config, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package idefix
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	sdkschema "github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/marty-macfly/goidefix/services/company"
+)
+
+var _ datasource.DataSourceWithConfigure = &companiesDataSource{}
+
+type companiesDataSource struct {
+	client *apiClient
+}
+
+type companiesDataSourceModel struct {
+	ID         types.String             `tfsdk:"id"`
+	NameFilter types.String             `tfsdk:"name_filter"`
+	Companies  []companyDataSourceModel `tfsdk:"companies"`
+}
+
+func newCompaniesDataSource() datasource.DataSource {
+	return &companiesDataSource{}
+}
+
+func (r *companiesDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_companies"
+}
+
+func (r *companiesDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Use this data source to access information about existing Companies.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "The ID of this resource.",
+			},
+			"name_filter": schema.StringAttribute{
+				Optional:    true,
+				Description: "Name to filter the list of companies.",
+			},
+			// Nested attributes need the protocol version 6, the provider
+			// is served with the version 5.
+			"companies": schema.ListAttribute{
+				Computed:    true,
+				Description: "The companies list, with the `id`, `name`, `code` and `status` of each company.",
+				ElementType: types.ObjectType{
+					AttrTypes: map[string]attr.Type{
+						"id":     types.Int64Type,
+						"name":   types.StringType,
+						"code":   types.StringType,
+						"status": types.StringType,
+					},
+				},
+			},
+		},
+	}
+}
+
+func (r *companiesDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*apiClient)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected data source configure type", fmt.Sprintf("Expected *apiClient, got: %T.", req.ProviderData))
+		return
+	}
+
+	r.client = client
+}
+
+func (r *companiesDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data companiesDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	search := &company.SearchRequest{
+		Name: data.NameFilter.ValueString(),
+	}
+
+	companies, err := r.client.Company.Search(ctx, search)
+	if isNotFound(err) {
+		companies, err = nil, nil
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to search the companies", err.Error())
+		return
+	}
+
+	data.ID = types.StringValue(strconv.Itoa(sdkschema.HashString(fmt.Sprintf("%+v", *search))))
+	data.Companies = flattenCompaniesData(companies)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func flattenCompaniesData(companies *[]company.SearchResponse) []companyDataSourceModel {
+	cs := make([]companyDataSourceModel, 0)
+
+	if companies != nil {
+		for _, c := range *companies {
+			cs = append(cs, companyDataSourceModel{
+				ID:     types.Int64Value(int64(c.ID)),
+				Name:   types.StringValue(c.Name),
+				Code:   types.StringValue(c.Code),
+				Status: types.StringValue(c.Status),
+			})
+		}
+	}
+
+	return cs
+}
@@ -0,0 +1,37 @@
+package idefix
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccDataSourceCompanies_basic(t *testing.T) {
+	api := testAccIdefix(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV5ProviderFactories: testAccProtoV5ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(api, fmt.Sprintf(`
+data "idefix_companies" "test" {
+  name_filter = %q
+}
+
+data "idefix_companies" "none" {
+  name_filter = "tf-acc-missing"
+}
+`, api.CompanyName)),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckTypeSetElemNestedAttrs("data.idefix_companies.test", "companies.*", map[string]string{
+						"id":   strconv.Itoa(api.CompanyID),
+						"name": api.CompanyName,
+					}),
+					resource.TestCheckResourceAttr("data.idefix_companies.none", "companies.#", "0"),
+				),
+			},
+		},
+	})
+}
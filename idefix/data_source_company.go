@@ -0,0 +1,172 @@
+package idefix
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/marty-macfly/goidefix/services/company"
+)
+
+var (
+	_ datasource.DataSourceWithConfigure      = &companyDataSource{}
+	_ datasource.DataSourceWithValidateConfig = &companyDataSource{}
+)
+
+type companyDataSource struct {
+	client *apiClient
+}
+
+type companyDataSourceModel struct {
+	ID     types.Int64  `tfsdk:"id"`
+	Name   types.String `tfsdk:"name"`
+	Code   types.String `tfsdk:"code"`
+	Status types.String `tfsdk:"status"`
+}
+
+func newCompanyDataSource() datasource.DataSource {
+	return &companyDataSource{}
+}
+
+func (r *companyDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_company"
+}
+
+func (r *companyDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Use this data source to access information about an existing Company.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.Int64Attribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "The ID of this resource. Either `id` or `name` must be set.",
+			},
+			"name": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "The name of the company. Either `id` or `name` must be set.",
+			},
+			"code": schema.StringAttribute{
+				Computed:    true,
+				Description: "The code of the company.",
+			},
+			"status": schema.StringAttribute{
+				Computed:    true,
+				Description: "The status of the company.",
+			},
+		},
+	}
+}
+
+func (r *companyDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*apiClient)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected data source configure type", fmt.Sprintf("Expected *apiClient, got: %T.", req.ProviderData))
+		return
+	}
+
+	r.client = client
+}
+
+func (r *companyDataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	var config companyDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if config.ID.IsUnknown() || config.Name.IsUnknown() {
+		return
+	}
+
+	if config.ID.IsNull() == config.Name.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("id"), "Invalid attribute combination", "Exactly one of `id` or `name` must be set.")
+	}
+}
+
+func (r *companyDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data companyDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	id := strconv.FormatInt(data.ID.ValueInt64(), 10)
+	if data.ID.IsNull() {
+		var err error
+
+		id, err = dataSourceCompanySearch(ctx, data.Name.ValueString(), r.client)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("name"), "Unable to find the company", err.Error())
+			return
+		}
+	}
+
+	company, err := r.client.Company.Read(ctx, &company.ReadRequest{
+		ID: id,
+	})
+	if isNotFound(err) || (err == nil && company == nil) {
+		resp.Diagnostics.AddError("Unable to read the company", fmt.Sprintf("company %s not found", id))
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to read the company", err.Error())
+		return
+	}
+
+	companyID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to parse the company ID", err.Error())
+		return
+	}
+
+	data.ID = types.Int64Value(companyID)
+	data.Name = types.StringValue(company.Name)
+	data.Code = types.StringValue(company.Code)
+	data.Status = types.StringValue(company.Status)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// dataSourceCompanySearch resolves the ID of the company matching exactly the
+// given name.
+func dataSourceCompanySearch(ctx context.Context, name string, client *apiClient) (string, error) {
+	resp, err := client.Company.Search(ctx, &company.SearchRequest{
+		Name: name,
+	})
+	if isNotFound(err) {
+		resp, err = nil, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var ids []string
+	if resp != nil {
+		for _, c := range *resp {
+			if c.Name == name {
+				ids = append(ids, strconv.Itoa(c.ID))
+			}
+		}
+	}
+
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("company %q not found", name)
+	case 1:
+		return ids[0], nil
+	default:
+		return "", fmt.Errorf("%d companies named %q found (IDs: %s)", len(ids), name, strings.Join(ids, ", "))
+	}
+}
@@ -0,0 +1,45 @@
+package idefix
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccDataSourceCompany_basic(t *testing.T) {
+	api := testAccIdefix(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV5ProviderFactories: testAccProtoV5ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(api, fmt.Sprintf(`
+data "idefix_company" "by_id" {
+  id = %d
+}
+
+data "idefix_company" "by_name" {
+  name = %q
+}
+`, api.CompanyID, api.CompanyName)),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.idefix_company.by_id", "name", api.CompanyName),
+					resource.TestCheckResourceAttr("data.idefix_company.by_id", "status", "Active"),
+					resource.TestCheckResourceAttr("data.idefix_company.by_name", "id", strconv.Itoa(api.CompanyID)),
+				),
+			},
+			{
+				Config: testAccConfig(api, `
+data "idefix_company" "test" {
+  id   = 1
+  name = "both"
+}
+`),
+				ExpectError: regexp.MustCompile("Exactly one of `id` or `name` must be set"),
+			},
+		},
+	})
+}
@@ -2,61 +2,228 @@ package idefix
 
 import (
 	"context"
+	"fmt"
 	"strconv"
+	"strings"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/marty-macfly/goidefix"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/marty-macfly/goidefix/services/project"
 )
 
-func dataSourceProject() *schema.Resource {
-	return &schema.Resource{
-		ReadContext: dataSourceProjectRead,
+var (
+	_ datasource.DataSourceWithConfigure      = &projectDataSource{}
+	_ datasource.DataSourceWithValidateConfig = &projectDataSource{}
+)
+
+type projectDataSource struct {
+	client *apiClient
+}
+
+type projectDataSourceModel struct {
+	ID             types.Int64  `tfsdk:"id"`
+	Name           types.String `tfsdk:"name"`
+	CompanyID      types.Int64  `tfsdk:"company_id"`
+	ParentID       types.Int64  `tfsdk:"parent_id"`
+	ContractNumber types.String `tfsdk:"contract_number"`
+	TypeName       types.String `tfsdk:"type_name"`
+	InvoiceType    types.String `tfsdk:"invoice_type"`
+	InitialBudget  types.String `tfsdk:"initial_budget"`
+	WbsFrance      types.String `tfsdk:"wbs_france"`
+	WbsVietnam     types.String `tfsdk:"wbs_vietnam"`
+	WbsSingapour   types.String `tfsdk:"wbs_singapour"`
+	WbsMaurice     types.String `tfsdk:"wbs_maurice"`
+	WbsLuxembourg  types.String `tfsdk:"wbs_luxembourg"`
+	WbsHongKong    types.String `tfsdk:"wbs_hong_kong"`
+	WbsChine       types.String `tfsdk:"wbs_chine"`
+	WbsCanada      types.String `tfsdk:"wbs_canada"`
+	WbsBelgique    types.String `tfsdk:"wbs_belgique"`
+}
+
+func newProjectDataSource() datasource.DataSource {
+	return &projectDataSource{}
+}
+
+func (r *projectDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_project"
+}
+
+func (r *projectDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	computed := func(description string) schema.StringAttribute {
+		return schema.StringAttribute{
+			Computed:    true,
+			Description: description,
+		}
+	}
+
+	resp.Schema = schema.Schema{
 		Description: "Use this data source to access information about an existing Project.",
-		Schema: map[string]*schema.Schema{
-			"id": {
-				Type:        schema.TypeInt,
-				Required:    true,
-				Description: "The ID of this resource.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.Int64Attribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "The ID of this resource. Either `id` or `name` must be set.",
 			},
-			"name": {
-				Type:        schema.TypeString,
+			"name": schema.StringAttribute{
+				Optional:    true,
 				Computed:    true,
-				Description: "The name of the project.",
+				Description: "The name of the project. Either `id` or `name` must be set.",
 			},
-			"company_id": {
-				Type:        schema.TypeInt,
+			"company_id": schema.Int64Attribute{
+				Optional:    true,
 				Computed:    true,
-				Description: "The company ID.",
+				Description: "The company ID. It restricts the lookup when the project is looked up by `name`.",
 			},
-			"parent_id": {
-				Type:        schema.TypeInt,
+			"parent_id": schema.Int64Attribute{
 				Computed:    true,
 				Description: "The ID of the parent project.",
 			},
+			"contract_number": computed("The contract number of the project."),
+			"type_name":       computed("The type of the project."),
+			"invoice_type":    computed("The invoice type of the project."),
+			"initial_budget":  computed("The initial budget of the project."),
+			"wbs_france":      computed("The WBS of the project"),
+			"wbs_vietnam":     computed("The WBS of the project"),
+			"wbs_singapour":   computed("The WBS of the project"),
+			"wbs_maurice":     computed("The WBS of the project"),
+			"wbs_luxembourg":  computed("The WBS of the project"),
+			"wbs_hong_kong":   computed("The WBS of the project"),
+			"wbs_chine":       computed("The WBS of the project"),
+			"wbs_canada":      computed("The WBS of the project"),
+			"wbs_belgique":    computed("The WBS of the project"),
 		},
 	}
 }
 
-func dataSourceProjectRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	var diags diag.Diagnostics
+func (r *projectDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*apiClient)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected data source configure type", fmt.Sprintf("Expected *apiClient, got: %T.", req.ProviderData))
+		return
+	}
+
+	r.client = client
+}
+
+func (r *projectDataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	var config projectDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-	id := strconv.Itoa(d.Get("id").(int))
+	if config.ID.IsUnknown() || config.Name.IsUnknown() {
+		return
+	}
 
-	client := m.(*goidefix.Idefix)
-	project, err := client.Project.Read(ctx, &project.ReadRequest{
+	if config.ID.IsNull() == config.Name.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("id"), "Invalid attribute combination", "Exactly one of `id` or `name` must be set.")
+	}
+}
+
+func (r *projectDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data projectDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	id := strconv.FormatInt(data.ID.ValueInt64(), 10)
+	if data.ID.IsNull() {
+		var diags diag.Diagnostics
+
+		id, diags = dataSourceProjectSearch(ctx, data.Name.ValueString(), int(data.CompanyID.ValueInt64()), r.client)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	project, err := r.client.Project.Read(ctx, &project.ReadRequest{
 		ID: id,
 	})
+	if isNotFound(err) || (err == nil && project == nil) {
+		resp.Diagnostics.AddAttributeError(path.Root("id"), "Project not found", fmt.Sprintf("No project with ID %s exists in Idefix.", id))
+		return
+	}
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddAttributeError(path.Root("id"), "Unable to read project", err.Error())
+		return
 	}
 
-	d.Set("name", project.Name)
-	d.Set("company_id", project.CompanyID)
-	d.Set("parent_id", project.ParentID)
+	projectID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to parse the project ID", err.Error())
+		return
+	}
 
-	d.SetId(id)
+	data.ID = types.Int64Value(projectID)
+	data.Name = types.StringValue(project.Name)
+	data.CompanyID = types.Int64Value(int64(project.CompanyID))
+	data.ParentID = types.Int64Value(int64(project.ParentID))
+	data.ContractNumber = types.StringValue(project.ContractNumber)
+	data.TypeName = types.StringValue(project.TypeName)
+	data.InvoiceType = types.StringValue(project.InvoiceType)
+	data.InitialBudget = types.StringValue(project.InitialBudget)
+	data.WbsFrance = types.StringValue(project.WbsFrance)
+	data.WbsVietnam = types.StringValue(project.WbsVietnam)
+	data.WbsSingapour = types.StringValue(project.WbsSingapour)
+	data.WbsMaurice = types.StringValue(project.WbsMaurice)
+	data.WbsLuxembourg = types.StringValue(project.WbsLuxembourg)
+	data.WbsHongKong = types.StringValue(project.WbsHongKong)
+	data.WbsChine = types.StringValue(project.WbsChine)
+	data.WbsCanada = types.StringValue(project.WbsCanada)
+	data.WbsBelgique = types.StringValue(project.WbsBelgique)
 
-	return diags
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// dataSourceProjectSearch resolves the ID of the project matching exactly
+// the name, and optionally the company, given in the configuration.
+func dataSourceProjectSearch(ctx context.Context, name string, companyID int, client *apiClient) (string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	resp, err := client.Project.Search(ctx, &project.SearchRequest{
+		Name:      name,
+		CompanyID: companyID,
+	})
+	if isNotFound(err) {
+		resp, err = nil, nil
+	}
+	if err != nil {
+		diags.AddAttributeError(path.Root("name"), "Unable to search project", err.Error())
+		return "", diags
+	}
+
+	// The company is checked again client-side, as findProjectByName does,
+	// so that a filter ignored by Idefix cannot return a project of another
+	// company.
+	var ids []string
+	if resp != nil {
+		for _, p := range *resp {
+			if p.Name == name && (companyID == 0 || p.CompanyID == companyID) {
+				ids = append(ids, strconv.Itoa(p.ID))
+			}
+		}
+	}
+
+	switch len(ids) {
+	case 0:
+		diags.AddAttributeError(path.Root("name"), "Project not found", fmt.Sprintf("No project named %q exists in Idefix.", name))
+		return "", diags
+	case 1:
+		return ids[0], nil
+	default:
+		diags.AddAttributeError(path.Root("name"), "Multiple projects found", fmt.Sprintf("%d projects named %q exist in Idefix (IDs: %s), set company_id to narrow the search.", len(ids), name, strings.Join(ids, ", ")))
+		return "", diags
+	}
 }
@@ -0,0 +1,105 @@
+package idefix
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/marty-macfly/goidefix/services/project"
+)
+
+func TestAccDataSourceProject_basic(t *testing.T) {
+	api := testAccIdefix(t)
+
+	id := api.createProject(t, project.CreateRequest{
+		Name:           "tf-acc-project",
+		ContractNumber: "C-0001",
+		WbsFrance:      "FR-0001",
+	})
+
+	resource.Test(t, resource.TestCase{
+		ProtoV5ProviderFactories: testAccProtoV5ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(api, fmt.Sprintf(`
+data "idefix_project" "by_id" {
+  id = %s
+}
+
+data "idefix_project" "by_name" {
+  name       = "tf-acc-project"
+  company_id = %d
+}
+`, id, api.CompanyID)),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.idefix_project.by_id", "name", "tf-acc-project"),
+					resource.TestCheckResourceAttr("data.idefix_project.by_id", "contract_number", "C-0001"),
+					resource.TestCheckResourceAttr("data.idefix_project.by_id", "wbs_france", "FR-0001"),
+					resource.TestCheckResourceAttr("data.idefix_project.by_name", "id", id),
+				),
+			},
+			{
+				Config: testAccConfig(api, `
+data "idefix_project" "test" {
+  name = "tf-acc-missing"
+}
+`),
+				ExpectError: regexp.MustCompile("not found"),
+			},
+		},
+	})
+}
+
+func TestDataSourceProjectRead_diagnostics(t *testing.T) {
+	api := testFakeIdefix(t)
+
+	for i := 0; i < 2; i++ {
+		api.createProject(t, project.CreateRequest{
+			Name:      "tf-acc-twin",
+			CompanyID: api.CompanyID + i,
+		})
+	}
+
+	s := testProviderServer(t, api.Client)
+	for _, c := range []struct {
+		config  map[string]interface{}
+		summary string
+		path    string
+	}{
+		{map[string]interface{}{"id": 999999}, "Project not found", "id"},
+		{map[string]interface{}{"name": "tf-acc-missing"}, "Project not found", "name"},
+		{map[string]interface{}{"name": "tf-acc-twin"}, "Multiple projects found", "name"},
+	} {
+		_, diags := s.readDataSource("idefix_project", c.config)
+		if len(diags) != 1 || diags[0].Summary != c.summary || !diags[0].Attribute.Equal(testAttributePath(c.path)) {
+			t.Errorf("%v: got %s, want %q on %s", c.config, testDiagnostics(diags), c.summary, c.path)
+		}
+	}
+
+	// The company is checked client-side too, even when Idefix ignores it.
+	client := *api.Client
+	client.Project = companyIgnoringProjectAPI{client.Project}
+
+	s = testProviderServer(t, &client)
+	_, diags := s.readDataSource("idefix_project", map[string]interface{}{
+		"name":       "tf-acc-twin",
+		"company_id": api.CompanyID,
+	})
+	if testHasError(diags) {
+		t.Errorf("narrowed by company: %s", testDiagnostics(diags))
+	}
+}
+
+// companyIgnoringProjectAPI searches projects without their company filter.
+type companyIgnoringProjectAPI struct {
+	projectAPI
+}
+
+func (p companyIgnoringProjectAPI) Search(ctx context.Context, req *project.SearchRequest) (*[]project.SearchResponse, error) {
+	r := *req
+	r.CompanyID = 0
+
+	return p.projectAPI.Search(ctx, &r)
+}
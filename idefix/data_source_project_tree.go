@@ -0,0 +1,228 @@
+package idefix
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/marty-macfly/goidefix/services/project"
+)
+
+var _ datasource.DataSourceWithConfigure = &projectTreeDataSource{}
+
+type projectTreeDataSource struct {
+	client *apiClient
+}
+
+type projectTreeDataSourceModel struct {
+	ID               types.String                 `tfsdk:"id"`
+	RootID           types.Int64                  `tfsdk:"root_id"`
+	IncludeAncestors types.Bool                   `tfsdk:"include_ancestors"`
+	Descendants      []projectTreeDescendantModel `tfsdk:"descendants"`
+	Ancestors        []projectTreeAncestorModel   `tfsdk:"ancestors"`
+}
+
+type projectTreeDescendantModel struct {
+	ID       types.Int64  `tfsdk:"id"`
+	Name     types.String `tfsdk:"name"`
+	ParentID types.Int64  `tfsdk:"parent_id"`
+	Depth    types.Int64  `tfsdk:"depth"`
+	Path     []int64      `tfsdk:"path"`
+}
+
+type projectTreeAncestorModel struct {
+	ID       types.Int64  `tfsdk:"id"`
+	Name     types.String `tfsdk:"name"`
+	ParentID types.Int64  `tfsdk:"parent_id"`
+}
+
+func newProjectTreeDataSource() datasource.DataSource {
+	return &projectTreeDataSource{}
+}
+
+func (r *projectTreeDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_project_tree"
+}
+
+func (r *projectTreeDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Use this data source to walk the hierarchy of an existing Project.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "The ID of this resource.",
+			},
+			"root_id": schema.Int64Attribute{
+				Required:    true,
+				Description: "The ID of the project the hierarchy is walked from.",
+			},
+			"include_ancestors": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Whether the ancestors of the root project are returned too. Defaults to `false`.",
+			},
+			// Nested attributes need the protocol version 6, the provider
+			// is served with the version 5.
+			"descendants": schema.ListAttribute{
+				Computed:    true,
+				Description: "The projects nested below the root project, one level at a time and sorted by ID within a level. Each has the `id`, `name` and `parent_id` of the project, its `depth` below the root project, starting at 1 for its direct children, and the `path` of the IDs of the projects from the root project to it, both included.",
+				ElementType: types.ObjectType{
+					AttrTypes: map[string]attr.Type{
+						"id":        types.Int64Type,
+						"name":      types.StringType,
+						"parent_id": types.Int64Type,
+						"depth":     types.Int64Type,
+						"path":      types.ListType{ElemType: types.Int64Type},
+					},
+				},
+			},
+			"ancestors": schema.ListAttribute{
+				Computed:    true,
+				Description: "The ancestors of the root project, from its parent to the top-level project, with the `id`, `name` and `parent_id` of each project.",
+				ElementType: types.ObjectType{
+					AttrTypes: map[string]attr.Type{
+						"id":        types.Int64Type,
+						"name":      types.StringType,
+						"parent_id": types.Int64Type,
+					},
+				},
+			},
+		},
+	}
+}
+
+func (r *projectTreeDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*apiClient)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected data source configure type", fmt.Sprintf("Expected *apiClient, got: %T.", req.ProviderData))
+		return
+	}
+
+	r.client = client
+}
+
+func (r *projectTreeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data projectTreeDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	rootID := int(data.RootID.ValueInt64())
+
+	root, err := r.client.Project.Read(ctx, &project.ReadRequest{
+		ID: strconv.Itoa(rootID),
+	})
+	if isNotFound(err) || (err == nil && root == nil) {
+		resp.Diagnostics.AddAttributeError(path.Root("root_id"), "Project not found", fmt.Sprintf("project %d not found", rootID))
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("root_id"), "Unable to read project", err.Error())
+		return
+	}
+
+	// The hierarchy is resolved from the projects of the company, fetched
+	// once.
+	companyProjects, err := r.client.Project.Search(ctx, &project.SearchRequest{
+		CompanyID: root.CompanyID,
+	})
+	if isNotFound(err) {
+		companyProjects, err = nil, nil
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to search the projects", err.Error())
+		return
+	}
+
+	var projects []project.SearchResponse
+	if companyProjects != nil {
+		projects = *companyProjects
+	}
+
+	data.Descendants = make([]projectTreeDescendantModel, 0)
+	for _, p := range projectDescendants(projects, rootID) {
+		ids := make([]int64, len(p.Path))
+		for i, id := range p.Path {
+			ids[i] = int64(id)
+		}
+
+		data.Descendants = append(data.Descendants, projectTreeDescendantModel{
+			ID:       types.Int64Value(int64(p.ID)),
+			Name:     types.StringValue(p.Name),
+			ParentID: types.Int64Value(int64(p.ParentID)),
+			Depth:    types.Int64Value(int64(p.Depth)),
+			Path:     ids,
+		})
+	}
+
+	data.Ancestors = make([]projectTreeAncestorModel, 0)
+	if data.IncludeAncestors.ValueBool() {
+		data.Ancestors, err = projectTreeAncestors(ctx, r.client, projects, rootID, root.ParentID)
+		if err != nil {
+			resp.Diagnostics.AddError("Unable to read the ancestors of the project", err.Error())
+			return
+		}
+	}
+
+	data.ID = types.StringValue(strconv.Itoa(rootID))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// projectTreeAncestors follows the parents of the project id up to the
+// top-level project. The parents are looked up in projects, and only read
+// from Idefix when they belong to another company.
+func projectTreeAncestors(ctx context.Context, client *apiClient, projects []project.SearchResponse, id int, parentID int) ([]projectTreeAncestorModel, error) {
+	byID := make(map[int]project.SearchResponse, len(projects))
+	for _, p := range projects {
+		byID[p.ID] = p
+	}
+
+	ancestors := make([]projectTreeAncestorModel, 0)
+
+	seen := map[int]bool{id: true}
+	for parentID != 0 && !seen[parentID] {
+		seen[parentID] = true
+
+		if p, ok := byID[parentID]; ok {
+			ancestors = append(ancestors, projectTreeAncestorModel{
+				ID:       types.Int64Value(int64(parentID)),
+				Name:     types.StringValue(p.Name),
+				ParentID: types.Int64Value(int64(p.ParentID)),
+			})
+
+			parentID = p.ParentID
+			continue
+		}
+
+		p, err := client.Project.Read(ctx, &project.ReadRequest{
+			ID: strconv.Itoa(parentID),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			break
+		}
+
+		ancestors = append(ancestors, projectTreeAncestorModel{
+			ID:       types.Int64Value(int64(parentID)),
+			Name:     types.StringValue(p.Name),
+			ParentID: types.Int64Value(int64(p.ParentID)),
+		})
+
+		parentID = p.ParentID
+	}
+
+	return ancestors, nil
+}
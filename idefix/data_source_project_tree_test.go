@@ -0,0 +1,123 @@
+package idefix
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/marty-macfly/goidefix/services/project"
+)
+
+func TestAccDataSourceProjectTree_basic(t *testing.T) {
+	api := testAccIdefix(t)
+
+	rootID := api.createProject(t, project.CreateRequest{
+		Name: "tf-acc-root",
+	})
+	root, _ := strconv.Atoi(rootID)
+	childID := api.createProject(t, project.CreateRequest{
+		Name:     "tf-acc-child",
+		ParentID: root,
+	})
+	child, _ := strconv.Atoi(childID)
+	grandchildID := api.createProject(t, project.CreateRequest{
+		Name:     "tf-acc-grandchild",
+		ParentID: child,
+	})
+
+	resource.Test(t, resource.TestCase{
+		ProtoV5ProviderFactories: testAccProtoV5ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(api, fmt.Sprintf(`
+data "idefix_project_tree" "root" {
+  root_id = %s
+}
+
+data "idefix_project_tree" "grandchild" {
+  root_id           = %s
+  include_ancestors = true
+}
+`, rootID, grandchildID)),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.idefix_project_tree.root", "descendants.#", "2"),
+					resource.TestCheckResourceAttr("data.idefix_project_tree.root", "descendants.0.id", childID),
+					resource.TestCheckResourceAttr("data.idefix_project_tree.root", "descendants.0.depth", "1"),
+					resource.TestCheckResourceAttr("data.idefix_project_tree.root", "descendants.1.id", grandchildID),
+					resource.TestCheckResourceAttr("data.idefix_project_tree.root", "descendants.1.depth", "2"),
+					resource.TestCheckResourceAttr("data.idefix_project_tree.grandchild", "descendants.#", "0"),
+					resource.TestCheckResourceAttr("data.idefix_project_tree.grandchild", "ancestors.#", "2"),
+				),
+			},
+		},
+	})
+}
+
+// searchCountingProjectAPI counts the searches of projects.
+type searchCountingProjectAPI struct {
+	projectAPI
+	searches *int
+}
+
+func (p searchCountingProjectAPI) Search(ctx context.Context, req *project.SearchRequest) (*[]project.SearchResponse, error) {
+	*p.searches++
+
+	return p.projectAPI.Search(ctx, req)
+}
+
+func TestDataSourceProjectTreeRead(t *testing.T) {
+	api := testFakeIdefix(t)
+
+	ids := make(map[string]int)
+	for _, p := range []struct{ name, parent string }{
+		{"root", ""},
+		{"child-1", "root"},
+		{"child-2", "root"},
+		{"grandchild-1", "child-1"},
+		{"grandchild-2", "child-2"},
+		{"other", ""},
+	} {
+		id, _ := strconv.Atoi(api.createProject(t, project.CreateRequest{
+			Name:      "tf-acc-" + p.name,
+			CompanyID: api.CompanyID,
+			ParentID:  ids[p.parent],
+		}))
+		ids[p.name] = id
+	}
+
+	var searches int
+	client := *api.Client
+	client.Project = searchCountingProjectAPI{client.Project, &searches}
+
+	s := testProviderServer(t, &client)
+	state, diags := s.readDataSource("idefix_project_tree", map[string]interface{}{
+		"root_id":           ids["root"],
+		"include_ancestors": true,
+	})
+	if testHasError(diags) {
+		t.Fatal(testDiagnostics(diags))
+	}
+
+	if searches != 1 {
+		t.Errorf("got %d searches of projects, want 1", searches)
+	}
+
+	descendants := testAttrs(state)["descendants"].([]interface{})
+
+	var got []int
+	for _, p := range descendants {
+		got = append(got, p.(map[string]interface{})["id"].(int))
+	}
+	want := []int{ids["child-1"], ids["child-2"], ids["grandchild-1"], ids["grandchild-2"]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got descendants %v, want %v", got, want)
+	}
+
+	path := descendants[3].(map[string]interface{})["path"]
+	if !reflect.DeepEqual(path, []interface{}{ids["root"], ids["child-2"], ids["grandchild-2"]}) {
+		t.Errorf("got path %v for grandchild-2", path)
+	}
+}
@@ -2,46 +2,121 @@ package idefix
 
 import (
 	"context"
+	"fmt"
+	"regexp"
+	"sort"
 	"strconv"
-	"time"
+	"strings"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/marty-macfly/goidefix"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	sdkschema "github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/marty-macfly/goidefix/services/project"
 )
 
-func dataSourceProjects() *schema.Resource {
-	return &schema.Resource{
-		ReadContext: dataSourceProjectsRead,
+var (
+	_ datasource.DataSourceWithConfigure      = &projectsDataSource{}
+	_ datasource.DataSourceWithValidateConfig = &projectsDataSource{}
+)
+
+type projectsDataSource struct {
+	client *apiClient
+}
+
+type projectsDataSourceModel struct {
+	ID              types.String                     `tfsdk:"id"`
+	NameFilter      types.String                     `tfsdk:"name_filter"`
+	ExactName       types.Bool                       `tfsdk:"exact_name"`
+	NameRegex       types.String                     `tfsdk:"name_regex"`
+	CompanyID       types.Int64                      `tfsdk:"company_id"`
+	ParentID        types.Int64                      `tfsdk:"parent_id"`
+	IncludeChildren types.Bool                       `tfsdk:"include_children"`
+	Projects        []projectsDataSourceProjectModel `tfsdk:"projects"`
+}
+
+type projectsDataSourceProjectModel struct {
+	ID             types.Int64  `tfsdk:"id"`
+	Name           types.String `tfsdk:"name"`
+	CompanyID      types.Int64  `tfsdk:"company_id"`
+	ParentID       types.Int64  `tfsdk:"parent_id"`
+	ContractNumber types.String `tfsdk:"contract_number"`
+	WbsFrance      types.String `tfsdk:"wbs_france"`
+	WbsVietnam     types.String `tfsdk:"wbs_vietnam"`
+	WbsSingapour   types.String `tfsdk:"wbs_singapour"`
+	WbsMaurice     types.String `tfsdk:"wbs_maurice"`
+	WbsLuxembourg  types.String `tfsdk:"wbs_luxembourg"`
+	WbsHongKong    types.String `tfsdk:"wbs_hong_kong"`
+	WbsChine       types.String `tfsdk:"wbs_chine"`
+	WbsCanada      types.String `tfsdk:"wbs_canada"`
+	WbsBelgique    types.String `tfsdk:"wbs_belgique"`
+}
+
+func newProjectsDataSource() datasource.DataSource {
+	return &projectsDataSource{}
+}
+
+func (r *projectsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_projects"
+}
+
+func (r *projectsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
 		Description: "Use this data source to access information about existing Projects.",
-		Schema: map[string]*schema.Schema{
-			"id": {
-				Type:        schema.TypeString,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
 				Computed:    true,
 				Description: "The ID of this resource.",
 			},
-			"name_filter": {
-				Type:        schema.TypeString,
-				Required:    true,
+			"name_filter": schema.StringAttribute{
+				Optional:    true,
 				Description: "Name to filter the list of projects.",
 			},
-			"projects": {
-				Type:        schema.TypeList,
+			"exact_name": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Whether the name of the projects must match `name_filter` exactly instead of containing it. Defaults to `false`.",
+			},
+			"name_regex": schema.StringAttribute{
+				Optional:    true,
+				Validators:  []validator.String{regexpValidator{}},
+				Description: "Regular expression the name of the projects must match.",
+			},
+			"company_id": schema.Int64Attribute{
+				Optional:    true,
+				Description: "Company ID to filter the list of projects.",
+			},
+			"parent_id": schema.Int64Attribute{
+				Optional:    true,
+				Description: "Parent project ID to filter the list of projects.",
+			},
+			"include_children": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Whether the projects nested below the direct children of `parent_id` are returned too. Defaults to `false`.",
+			},
+			// Nested attributes need the protocol version 6, the provider
+			// is served with the version 5.
+			"projects": schema.ListAttribute{
 				Computed:    true,
-				Description: "The projects list.",
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"id": {
-							Type:        schema.TypeInt,
-							Computed:    true,
-							Description: "The ID of the project.",
-						},
-						"name": {
-							Type:        schema.TypeString,
-							Computed:    true,
-							Description: "The Name of the project.",
-						},
+				Description: "The projects list, with the `id`, `name`, `company_id`, `parent_id`, `contract_number` and `wbs_*` of each project.",
+				ElementType: types.ObjectType{
+					AttrTypes: map[string]attr.Type{
+						"id":              types.Int64Type,
+						"name":            types.StringType,
+						"company_id":      types.Int64Type,
+						"parent_id":       types.Int64Type,
+						"contract_number": types.StringType,
+						"wbs_france":      types.StringType,
+						"wbs_vietnam":     types.StringType,
+						"wbs_singapour":   types.StringType,
+						"wbs_maurice":     types.StringType,
+						"wbs_luxembourg":  types.StringType,
+						"wbs_hong_kong":   types.StringType,
+						"wbs_chine":       types.StringType,
+						"wbs_canada":      types.StringType,
+						"wbs_belgique":    types.StringType,
 					},
 				},
 			},
@@ -49,42 +124,218 @@ func dataSourceProjects() *schema.Resource {
 	}
 }
 
-func dataSourceProjectsRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	var diags diag.Diagnostics
+func (r *projectsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
 
-	client := m.(*goidefix.Idefix)
-	resp, err := client.Project.Search(ctx, &project.SearchRequest{
-		Name: d.Get("name_filter").(string),
-	})
-	if err != nil {
-		return diag.FromErr(err)
+	client, ok := req.ProviderData.(*apiClient)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected data source configure type", fmt.Sprintf("Expected *apiClient, got: %T.", req.ProviderData))
+		return
 	}
 
-	projects := flattenProjectsData(resp)
-	if err := d.Set("projects", projects); err != nil {
-		return diag.FromErr(err)
+	r.client = client
+}
+
+// ValidateConfig checks that exact_name and include_children are only set
+// with the filter they apply to.
+func (r *projectsDataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	var config projectsDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	d.SetId(strconv.FormatInt(time.Now().Unix(), 10))
+	if !config.ExactName.IsNull() && config.NameFilter.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("exact_name"), "Missing required argument", "`name_filter` must be set when `exact_name` is set.")
+	}
 
-	return diags
+	if !config.IncludeChildren.IsNull() && config.ParentID.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("include_children"), "Missing required argument", "`parent_id` must be set when `include_children` is set.")
+	}
 }
 
-func flattenProjectsData(projects *[]project.SearchResponse) []interface{} {
+func (r *projectsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data projectsDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	name := data.NameFilter.ValueString()
+	exactName := data.ExactName.ValueBool()
+	nameRegex := data.NameRegex.ValueString()
+	parentID := int(data.ParentID.ValueInt64())
+	includeChildren := data.IncludeChildren.ValueBool()
+
+	search := &project.SearchRequest{
+		Name:      name,
+		CompanyID: int(data.CompanyID.ValueInt64()),
+		ParentID:  parentID,
+	}
+	if includeChildren {
+		// The descendants of the parent are resolved client-side, which
+		// needs the whole hierarchy including the intermediate projects.
+		search.Name = ""
+		search.ParentID = 0
+	}
+
+	projects, err := r.client.Project.Search(ctx, search)
+	if isNotFound(err) {
+		projects, err = nil, nil
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to search the projects", err.Error())
+		return
+	}
+
+	var re *regexp.Regexp
+	if nameRegex != "" {
+		re = regexp.MustCompile(nameRegex)
+	}
+
+	var descendants map[int]bool
+	if includeChildren && projects != nil {
+		descendants = make(map[int]bool)
+		for _, p := range projectDescendants(*projects, parentID) {
+			descendants[p.ID] = true
+		}
+	}
+
+	var filtered []project.SearchResponse
 	if projects != nil {
-		ps := make([]interface{}, len(*projects))
+		for _, p := range *projects {
+			if exactName && p.Name != name {
+				continue
+			}
+
+			if search.Name != name && !strings.Contains(strings.ToLower(p.Name), strings.ToLower(name)) {
+				continue
+			}
+
+			if re != nil && !re.MatchString(p.Name) {
+				continue
+			}
+
+			if includeChildren && !descendants[p.ID] {
+				continue
+			}
+
+			filtered = append(filtered, p)
+		}
+	}
+
+	data.ID = types.StringValue(strconv.Itoa(sdkschema.HashString(fmt.Sprintf("%s|%t|%s|%d|%d|%t", name, exactName, nameRegex, search.CompanyID, parentID, includeChildren))))
+	data.Projects = flattenProjectsData(&filtered)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// projectNode is a project nested below another one.
+type projectNode struct {
+	project.SearchResponse
+	// Depth is the depth of the project below the other one, starting at 1
+	// for its direct children.
+	Depth int
+	// Path holds the IDs of the projects from the other one to this one,
+	// both included.
+	Path []int
+}
+
+// projectDescendants returns all the projects nested, at any depth, below the
+// project parentID, one level at a time so that every project comes after its
+// parent. The siblings are sorted by ID.
+func projectDescendants(projects []project.SearchResponse, parentID int) []projectNode {
+	children := make(map[int][]project.SearchResponse)
+	for _, p := range projects {
+		children[p.ParentID] = append(children[p.ParentID], p)
+	}
+	for _, c := range children {
+		sort.Slice(c, func(i, j int) bool { return c[i].ID < c[j].ID })
+	}
 
-		for i, project := range *projects {
-			p := make(map[string]interface{})
+	descendants := make([]projectNode, 0)
 
-			p["id"] = project.ID
-			p["name"] = project.Name
+	// Guard against loops in the hierarchy.
+	seen := map[int]bool{parentID: true}
+	queue := []projectNode{{
+		SearchResponse: project.SearchResponse{ID: parentID},
+		Path:           []int{parentID},
+	}}
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
 
-			ps[i] = p
+		for _, p := range children[parent.ID] {
+			if seen[p.ID] {
+				continue
+			}
+			seen[p.ID] = true
+
+			path := make([]int, len(parent.Path), len(parent.Path)+1)
+			copy(path, parent.Path)
+
+			node := projectNode{
+				SearchResponse: p,
+				Depth:          parent.Depth + 1,
+				Path:           append(path, p.ID),
+			}
+			descendants = append(descendants, node)
+			queue = append(queue, node)
+		}
+	}
+
+	return descendants
+}
+
+func flattenProjectsData(projects *[]project.SearchResponse) []projectsDataSourceProjectModel {
+	ps := make([]projectsDataSourceProjectModel, 0)
+
+	if projects != nil {
+		for _, p := range *projects {
+			ps = append(ps, projectsDataSourceProjectModel{
+				ID:             types.Int64Value(int64(p.ID)),
+				Name:           types.StringValue(p.Name),
+				CompanyID:      types.Int64Value(int64(p.CompanyID)),
+				ParentID:       types.Int64Value(int64(p.ParentID)),
+				ContractNumber: types.StringValue(p.ContractNumber),
+				WbsFrance:      types.StringValue(p.WbsFrance),
+				WbsVietnam:     types.StringValue(p.WbsVietnam),
+				WbsSingapour:   types.StringValue(p.WbsSingapour),
+				WbsMaurice:     types.StringValue(p.WbsMaurice),
+				WbsLuxembourg:  types.StringValue(p.WbsLuxembourg),
+				WbsHongKong:    types.StringValue(p.WbsHongKong),
+				WbsChine:       types.StringValue(p.WbsChine),
+				WbsCanada:      types.StringValue(p.WbsCanada),
+				WbsBelgique:    types.StringValue(p.WbsBelgique),
+			})
 		}
+	}
+
+	return ps
+}
 
-		return ps
+// regexpValidator checks that a string is a regular expression the regexp
+// package understands.
+type regexpValidator struct{}
+
+func (v regexpValidator) Description(ctx context.Context) string {
+	return "The value must be a valid regular expression."
+}
+
+func (v regexpValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v regexpValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
 	}
 
-	return make([]interface{}, 0)
+	if _, err := regexp.Compile(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid regular expression", err.Error())
+	}
 }
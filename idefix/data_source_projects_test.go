@@ -0,0 +1,67 @@
+package idefix
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/marty-macfly/goidefix/services/project"
+)
+
+func TestAccDataSourceProjects_basic(t *testing.T) {
+	api := testAccIdefix(t)
+
+	parentID := api.createProject(t, project.CreateRequest{
+		Name: "tf-acc-parent",
+	})
+	parent, _ := strconv.Atoi(parentID)
+	childID := api.createProject(t, project.CreateRequest{
+		Name:     "tf-acc-child",
+		ParentID: parent,
+	})
+	child, _ := strconv.Atoi(childID)
+	api.createProject(t, project.CreateRequest{
+		Name:     "tf-acc-grandchild",
+		ParentID: child,
+	})
+
+	resource.Test(t, resource.TestCase{
+		ProtoV5ProviderFactories: testAccProtoV5ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(api, fmt.Sprintf(`
+data "idefix_projects" "all" {
+  name_filter = "tf-acc"
+}
+
+data "idefix_projects" "exact" {
+  name_filter = "tf-acc-child"
+  exact_name  = true
+}
+
+data "idefix_projects" "children" {
+  parent_id = %[1]d
+}
+
+data "idefix_projects" "descendants" {
+  parent_id        = %[1]d
+  include_children = true
+}
+
+data "idefix_projects" "regex" {
+  name_regex = "child$"
+}
+`, parent)),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.idefix_projects.all", "projects.#", "3"),
+					resource.TestCheckResourceAttr("data.idefix_projects.exact", "projects.#", "1"),
+					resource.TestCheckResourceAttr("data.idefix_projects.exact", "projects.0.id", childID),
+					resource.TestCheckResourceAttr("data.idefix_projects.children", "projects.#", "1"),
+					resource.TestCheckResourceAttr("data.idefix_projects.descendants", "projects.#", "2"),
+					resource.TestCheckResourceAttr("data.idefix_projects.regex", "projects.#", "2"),
+				),
+			},
+		},
+	})
+}
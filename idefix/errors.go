@@ -0,0 +1,33 @@
+package idefix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// isNotFound reports whether err means that the requested Idefix object does
+// not exist, that is whether the API answered with a 404 status code. Only
+// errors carrying their status code with a StatusCode method are trusted, not
+// their message.
+func isNotFound(err error) bool {
+	var sc interface{ StatusCode() int }
+
+	return errors.As(err, &sc) && sc.StatusCode() == http.StatusNotFound
+}
+
+// stepError annotates err with the step which was in progress, and points out
+// when the step was interrupted because the timeout of the operation elapsed.
+func stepError(ctx context.Context, err error, format string, a ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+
+	step := fmt.Sprintf(format, a...)
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timeout while %s: %w", step, err)
+	}
+
+	return fmt.Errorf("error %s: %w", step, err)
+}
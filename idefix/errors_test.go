@@ -0,0 +1,190 @@
+package idefix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/marty-macfly/goidefix/services/ci"
+	"github.com/marty-macfly/goidefix/services/project"
+	"github.com/marty-macfly/terraform-provider-idefix/internal/fakeidefix"
+)
+
+type statusError int
+
+func (e statusError) Error() string   { return fmt.Sprintf("status %d", int(e)) }
+func (e statusError) StatusCode() int { return int(e) }
+
+func TestIsNotFound(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("connection refused"), false},
+		{errors.New("404 Not Found"), false},
+		{fmt.Errorf("read CI: %w", statusError(http.StatusNotFound)), true},
+		{statusError(http.StatusInternalServerError), false},
+	}
+
+	for _, c := range cases {
+		if got := isNotFound(c.err); got != c.want {
+			t.Errorf("isNotFound(%v) = %t, want %t", c.err, got, c.want)
+		}
+	}
+}
+
+// TestIsNotFound_client checks isNotFound against the errors of the goidefix
+// client itself, decoded from the HTTP responses of the fake Idefix.
+func TestIsNotFound_client(t *testing.T) {
+	api := testFakeIdefix(t)
+	ctx := context.Background()
+
+	_, err := api.Client.Project.Read(ctx, &project.ReadRequest{ID: "1234"})
+	if !isNotFound(err) {
+		t.Errorf("reading a missing project: got %v (%T), want a not found error", err, err)
+	}
+	var fake *fakeidefix.Error
+	if errors.As(err, &fake) {
+		t.Errorf("reading a missing project: got an error of the fake, want one of goidefix")
+	}
+
+	_, err = api.Client.CI.Read(ctx, &ci.ReadRequest{ID: "1234"})
+	if !isNotFound(err) {
+		t.Errorf("reading a missing CI: got %v (%T), want a not found error", err, err)
+	}
+
+	_, err = newIdefixClient(ctx, api.URL, "tf-acc-login", "tf-acc-password")
+	if err == nil || isNotFound(err) {
+		t.Errorf("logging in with invalid credentials: got %v, want an error other than not found", err)
+	}
+}
+
+// notFoundCounter counts the reads made to an empty fake Idefix, and how many
+// of them failed with a typed not found error.
+type notFoundCounter struct {
+	reads    int
+	notFound int
+}
+
+func (c *notFoundCounter) count(err error) {
+	c.reads++
+	if isNotFound(err) {
+		c.notFound++
+	}
+}
+
+type countingProjectAPI struct {
+	projectAPI
+	counter *notFoundCounter
+}
+
+func (p countingProjectAPI) Read(ctx context.Context, req *project.ReadRequest) (*project.ReadResponse, error) {
+	resp, err := p.projectAPI.Read(ctx, req)
+	p.counter.count(err)
+
+	return resp, err
+}
+
+type countingCIAPI struct {
+	ciAPI
+	counter *notFoundCounter
+}
+
+func (c countingCIAPI) Read(ctx context.Context, req *ci.ReadRequest) (*ci.ReadResponse, error) {
+	resp, err := c.ciAPI.Read(ctx, req)
+	c.counter.count(err)
+
+	return resp, err
+}
+
+// newNotFoundClient returns a client of an empty fake Idefix, which answers
+// with a 404 for every object, counting the reads of projects and CIs.
+func newNotFoundClient(t *testing.T) (*apiClient, *notFoundCounter) {
+	t.Helper()
+
+	counter := &notFoundCounter{}
+	client := *testFakeIdefix(t).Client
+	client.Project = countingProjectAPI{client.Project, counter}
+	client.CI = countingCIAPI{client.CI, counter}
+
+	return &client, counter
+}
+
+// checkNotFoundReads fails the test unless the reads went through the typed
+// not found path.
+func checkNotFoundReads(t *testing.T, name string, counter *notFoundCounter) {
+	t.Helper()
+
+	if counter.reads == 0 {
+		t.Errorf("%s: Idefix was not called", name)
+	}
+	if counter.notFound != counter.reads {
+		t.Errorf("%s: %d of the %d reads failed with a not found error", name, counter.notFound, counter.reads)
+	}
+}
+
+func TestResourcesReadNotFound(t *testing.T) {
+	client, counter := newNotFoundClient(t)
+	s := testProviderServer(t, client)
+
+	for _, name := range []string{"idefix_project", "idefix_ci"} {
+		*counter = notFoundCounter{}
+
+		state := s.config(s.resourceSchema(name), map[string]interface{}{"id": "1234"})
+		state, diags := s.read(name, state)
+		if testHasError(diags) {
+			t.Errorf("%s: unexpected error: %s", name, testDiagnostics(diags))
+		}
+		if !state.IsNull() {
+			t.Errorf("%s: state = %s, want it removed", name, testCanonical(state))
+		}
+		checkNotFoundReads(t, name, counter)
+	}
+}
+
+func TestDataSourcesReadNotFound(t *testing.T) {
+	client, counter := newNotFoundClient(t)
+	s := testProviderServer(t, client)
+
+	dataSources := map[string]map[string]interface{}{
+		"idefix_project": {"id": 1234},
+		"idefix_ci":      {"id": "1234"},
+	}
+
+	for name, attrs := range dataSources {
+		*counter = notFoundCounter{}
+
+		_, diags := s.readDataSource(name, attrs)
+		if d := testDiagnostic(diags, tfprotov5.DiagnosticSeverityError); d == nil {
+			t.Errorf("%s: expected a not found error", name)
+		} else if msg := d.Summary + " " + d.Detail; !strings.Contains(msg, "not found") {
+			t.Errorf("%s: got error %q, want a not found error", name, msg)
+		}
+		checkNotFoundReads(t, name, counter)
+	}
+}
+
+func TestStepError(t *testing.T) {
+	if err := stepError(context.Background(), nil, "reading CI %s", "1234"); err != nil {
+		t.Errorf("stepError(nil) = %v, want nil", err)
+	}
+
+	err := stepError(context.Background(), errors.New("boom"), "reading CI %s", "1234")
+	if got, want := err.Error(), "error reading CI 1234: boom"; got != want {
+		t.Errorf("stepError() = %q, want %q", got, want)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	<-ctx.Done()
+
+	err = stepError(ctx, ctx.Err(), "updating the platform of CI %s", "1234")
+	if got, want := err.Error(), "timeout while updating the platform of CI 1234: context deadline exceeded"; got != want {
+		t.Errorf("stepError() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,61 @@
+package idefix
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// defaultModifier plans value for an Optional and Computed attribute left
+// unset in the configuration, as the Default of the SDK schema did. The
+// framework version the provider uses has no Default field.
+type defaultModifier struct {
+	value attr.Value
+}
+
+var (
+	_ planmodifier.String = defaultModifier{}
+	_ planmodifier.Int64  = defaultModifier{}
+	_ planmodifier.Bool   = defaultModifier{}
+)
+
+func defaultString(v string) planmodifier.String {
+	return defaultModifier{types.StringValue(v)}
+}
+
+func defaultInt64(v int64) planmodifier.Int64 {
+	return defaultModifier{types.Int64Value(v)}
+}
+
+func defaultBool(v bool) planmodifier.Bool {
+	return defaultModifier{types.BoolValue(v)}
+}
+
+func (m defaultModifier) Description(ctx context.Context) string {
+	return fmt.Sprintf("Defaults to %s.", m.value)
+}
+
+func (m defaultModifier) MarkdownDescription(ctx context.Context) string {
+	return fmt.Sprintf("Defaults to `%s`.", m.value)
+}
+
+func (m defaultModifier) PlanModifyString(ctx context.Context, req planmodifier.StringRequest, resp *planmodifier.StringResponse) {
+	if req.ConfigValue.IsNull() {
+		resp.PlanValue = m.value.(types.String)
+	}
+}
+
+func (m defaultModifier) PlanModifyInt64(ctx context.Context, req planmodifier.Int64Request, resp *planmodifier.Int64Response) {
+	if req.ConfigValue.IsNull() {
+		resp.PlanValue = m.value.(types.Int64)
+	}
+}
+
+func (m defaultModifier) PlanModifyBool(ctx context.Context, req planmodifier.BoolRequest, resp *planmodifier.BoolResponse) {
+	if req.ConfigValue.IsNull() {
+		resp.PlanValue = m.value.(types.Bool)
+	}
+}
@@ -5,12 +5,17 @@ import (
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/marty-macfly/goidefix"
-	"github.com/marty-macfly/goidefix/services/authentification"
 )
 
 func Provider() *schema.Provider {
-	return &schema.Provider{
+	return newProvider(&sharedClient{})
+}
+
+// newProvider returns the SDK provider, which serves the catalog data sources
+// not ported to the framework yet. Its client is shared with the framework
+// provider through clients.
+func newProvider(clients *sharedClient) *schema.Provider {
+	p := &schema.Provider{
 		Schema: map[string]*schema.Schema{
 			"url": {
 				Type:        schema.TypeString,
@@ -32,43 +37,27 @@ func Provider() *schema.Provider {
 				Description: "The password wich should be used. This can also be sourced from the `IDEFIX_PASSWORD` environment variable.",
 			},
 		},
-		ResourcesMap: map[string]*schema.Resource{
-			"idefix_project": resourceProject(),
-			"idefix_ci":      resourceCI(),
-		},
-		DataSourcesMap: map[string]*schema.Resource{
-			"idefix_project":  dataSourceProject(),
-			"idefix_projects": dataSourceProjects(),
-			"idefix_ci":       dataSourceCI(),
-		},
-		ConfigureContextFunc: providerConfigure,
+		ResourcesMap:         map[string]*schema.Resource{},
+		DataSourcesMap:       map[string]*schema.Resource{},
+		ConfigureContextFunc: providerConfigure(clients),
+	}
+
+	for _, c := range catalogs {
+		p.DataSourcesMap[c.Name] = dataSourceCatalog(c)
 	}
+
+	return p
 }
 
-func providerConfigure(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
-	var diags diag.Diagnostics
-	var client *goidefix.Idefix
-	var err error
+func providerConfigure(clients *sharedClient) schema.ConfigureContextFunc {
+	return func(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
+		var diags diag.Diagnostics
 
-	url := d.Get("url").(string)
-	login := d.Get("login").(string)
-	password := d.Get("password").(string)
+		client, err := clients.get(ctx, d.Get("url").(string), d.Get("login").(string), d.Get("password").(string))
+		if err != nil {
+			return nil, diag.FromErr(err)
+		}
 
-	if url == "" {
-		client, err = goidefix.New(ctx)
-	} else {
-		client, err = goidefix.NewWithEndpoint(ctx, url)
-	}
-	if err != nil {
-		return nil, diag.FromErr(err)
+		return client, diags
 	}
-	_, err = client.Authentification.Login(ctx, &authentification.LoginRequest{
-		Login:    login,
-		Password: password,
-	})
-	if err != nil {
-		return nil, diag.FromErr(err)
-	}
-
-	return client, diags
 }
@@ -0,0 +1,125 @@
+package idefix
+
+import (
+	"context"
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-mux/tf5muxserver"
+)
+
+var _ provider.Provider = &frameworkProvider{}
+
+// frameworkProvider is the part of the provider built on
+// terraform-plugin-framework. It is served next to the SDK provider through
+// terraform-plugin-mux while the catalog data sources are being ported,
+// so its configuration must stay the same as the one of Provider.
+type frameworkProvider struct {
+	clients *sharedClient
+}
+
+type frameworkProviderModel struct {
+	URL      types.String `tfsdk:"url"`
+	Login    types.String `tfsdk:"login"`
+	Password types.String `tfsdk:"password"`
+}
+
+// ProtoV5ProviderServerFactory returns the server of the provider. The SDK and
+// framework providers are served together while the catalog data sources are
+// being ported to the framework. They share the client they are
+// configured with, so that Idefix is logged in once.
+func ProtoV5ProviderServerFactory(ctx context.Context) (func() tfprotov5.ProviderServer, error) {
+	clients := &sharedClient{}
+	providers := []func() tfprotov5.ProviderServer{
+		newProvider(clients).GRPCProvider,
+		providerserver.NewProtocol5(&frameworkProvider{clients: clients}),
+	}
+
+	muxServer, err := tf5muxserver.NewMuxServer(ctx, providers...)
+	if err != nil {
+		return nil, err
+	}
+
+	return muxServer.ProviderServer, nil
+}
+
+func (p *frameworkProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
+	resp.TypeName = "idefix"
+}
+
+func (p *frameworkProvider) Schema(ctx context.Context, req provider.SchemaRequest, resp *provider.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"url": schema.StringAttribute{
+				Optional:    true,
+				Description: "This can be used to override the base URL for Idefix API. This can also be sourced from the `IDEFIX_URL` environment variable.",
+			},
+			"login": schema.StringAttribute{
+				Optional:    true,
+				Description: "The login wich should be used. This can also be sourced from the `IDEFIX_LOGIN` environment variable.",
+			},
+			"password": schema.StringAttribute{
+				Optional:    true,
+				Sensitive:   true,
+				Description: "The password wich should be used. This can also be sourced from the `IDEFIX_PASSWORD` environment variable.",
+			},
+		},
+	}
+}
+
+func (p *frameworkProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
+	var config frameworkProviderModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client, err := p.clients.get(ctx,
+		stringOrEnv(config.URL, "IDEFIX_URL"),
+		stringOrEnv(config.Login, "IDEFIX_LOGIN"),
+		stringOrEnv(config.Password, "IDEFIX_PASSWORD"),
+	)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to create the Idefix client", err.Error())
+		return
+	}
+
+	resp.DataSourceData = client
+	resp.ResourceData = client
+}
+
+func (p *frameworkProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
+	return []func() datasource.DataSource{
+		newCompanyDataSource,
+		newCompaniesDataSource,
+		newProjectDataSource,
+		newProjectsDataSource,
+		newProjectTreeDataSource,
+		newCIDataSource,
+		newCIsDataSource,
+	}
+}
+
+func (p *frameworkProvider) Resources(ctx context.Context) []func() resource.Resource {
+	return []func() resource.Resource{
+		newProjectResource,
+		newCIResource,
+	}
+}
+
+// stringOrEnv returns the configured value, falling back to the environment
+// variable like the schema.EnvDefaultFunc of the SDK provider does.
+func stringOrEnv(v types.String, env string) string {
+	if v.IsNull() || v.IsUnknown() {
+		return os.Getenv(env)
+	}
+
+	return v.ValueString()
+}
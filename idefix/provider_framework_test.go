@@ -0,0 +1,535 @@
+package idefix
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// testServer calls the provider server the way Terraform does, so that the
+// unit tests go through the schemas, validators, plan modifiers and state
+// upgraders of the resources and data sources, and check that their plans and
+// states are consistent as Terraform would.
+type testServer struct {
+	t       *testing.T
+	server  tfprotov5.ProviderServer
+	schemas *tfprotov5.GetProviderSchemaResponse
+}
+
+// testUnknown is the value testNative returns for an unknown value.
+const testUnknown = "<unknown>"
+
+// testProviderServer returns the provider server, configured so that its
+// resources and data sources call Idefix with client.
+func testProviderServer(t *testing.T, client *apiClient) *testServer {
+	t.Helper()
+
+	testSetNewClient(t, func(context.Context, string, string, string) (*apiClient, error) {
+		return client, nil
+	})
+
+	ctx := context.Background()
+
+	factory, err := ProtoV5ProviderServerFactory(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServer{t: t, server: factory()}
+
+	s.schemas, err = s.server.GetProviderSchema(ctx, &tfprotov5.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.fatalDiagnostics("getting the schemas", s.schemas.Diagnostics)
+
+	config := s.config(s.schemas.Provider, map[string]interface{}{
+		"url":      "https://idefix.invalid",
+		"login":    "login",
+		"password": "password",
+	})
+	resp, err := s.server.ConfigureProvider(ctx, &tfprotov5.ConfigureProviderRequest{
+		Config: s.dynamicValue(config),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.fatalDiagnostics("configuring the provider", resp.Diagnostics)
+
+	return s
+}
+
+// fatalDiagnostics fails the test when diags hold an error.
+func (s *testServer) fatalDiagnostics(what string, diags []*tfprotov5.Diagnostic) {
+	s.t.Helper()
+
+	if testHasError(diags) {
+		s.t.Fatalf("%s: %s", what, testDiagnostics(diags))
+	}
+}
+
+// testHasError reports whether diags hold an error.
+func testHasError(diags []*tfprotov5.Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == tfprotov5.DiagnosticSeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// testDiagnostics formats diags for the messages of the tests.
+func testDiagnostics(diags []*tfprotov5.Diagnostic) string {
+	msgs := make([]string, 0, len(diags))
+	for _, d := range diags {
+		msg := fmt.Sprintf("%s: %s: %s", d.Severity, d.Summary, d.Detail)
+		if d.Attribute != nil {
+			msg = d.Attribute.String() + ": " + msg
+		}
+		msgs = append(msgs, msg)
+	}
+
+	return "[" + strings.Join(msgs, "; ") + "]"
+}
+
+// testDiagnostic returns the first diagnostic of diags with the severity,
+// or nil.
+func testDiagnostic(diags []*tfprotov5.Diagnostic, severity tfprotov5.DiagnosticSeverity) *tfprotov5.Diagnostic {
+	for _, d := range diags {
+		if d.Severity == severity {
+			return d
+		}
+	}
+
+	return nil
+}
+
+// testAttributePath returns the path of the top-level attribute name.
+func testAttributePath(name string) *tftypes.AttributePath {
+	return tftypes.NewAttributePath().WithAttributeName(name)
+}
+
+func (s *testServer) resourceSchema(typeName string) *tfprotov5.Schema {
+	s.t.Helper()
+
+	schema, ok := s.schemas.ResourceSchemas[typeName]
+	if !ok {
+		s.t.Fatalf("no resource %s", typeName)
+	}
+
+	return schema
+}
+
+func (s *testServer) dataSourceSchema(typeName string) *tfprotov5.Schema {
+	s.t.Helper()
+
+	schema, ok := s.schemas.DataSourceSchemas[typeName]
+	if !ok {
+		s.t.Fatalf("no data source %s", typeName)
+	}
+
+	return schema
+}
+
+func (s *testServer) dynamicValue(v tftypes.Value) *tfprotov5.DynamicValue {
+	s.t.Helper()
+
+	dv, err := tfprotov5.NewDynamicValue(v.Type(), v)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	return &dv
+}
+
+func (s *testServer) value(schema *tfprotov5.Schema, dv *tfprotov5.DynamicValue) tftypes.Value {
+	s.t.Helper()
+
+	if dv == nil {
+		return tftypes.NewValue(schema.ValueType(), nil)
+	}
+
+	v, err := dv.Unmarshal(schema.ValueType())
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	return v
+}
+
+// config returns the configuration of schema setting attrs, with the blocks
+// which attrs leave out set to no block, as Terraform does. The
+// configuration is null when attrs is nil.
+func (s *testServer) config(schema *tfprotov5.Schema, attrs map[string]interface{}) tftypes.Value {
+	s.t.Helper()
+
+	typ := schema.ValueType()
+	if attrs == nil {
+		return tftypes.NewValue(typ, nil)
+	}
+
+	b, err := json.Marshal(attrs)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	v, err := tftypes.ValueFromJSONWithOpts(b, typ, tftypes.ValueFromJSONOpts{})
+	if err != nil {
+		s.t.Fatalf("decoding the configuration %s: %s", b, err)
+	}
+
+	var values map[string]tftypes.Value
+	if err := v.As(&values); err != nil {
+		s.t.Fatal(err)
+	}
+	for _, block := range schema.Block.BlockTypes {
+		if block.Nesting != tfprotov5.SchemaNestedBlockNestingModeSet && block.Nesting != tfprotov5.SchemaNestedBlockNestingModeList {
+			continue
+		}
+
+		if values[block.TypeName].IsNull() {
+			values[block.TypeName] = tftypes.NewValue(values[block.TypeName].Type(), []tftypes.Value{})
+		}
+	}
+
+	return tftypes.NewValue(typ, values)
+}
+
+// proposedNewState merges config into prior the way Terraform does for the
+// top-level attributes: the computed attributes left out of the configuration
+// keep their prior value.
+func (s *testServer) proposedNewState(schema *tfprotov5.Schema, prior tftypes.Value, config tftypes.Value) tftypes.Value {
+	s.t.Helper()
+
+	if prior.IsNull() || config.IsNull() {
+		return config
+	}
+
+	var priorValues, values map[string]tftypes.Value
+	if err := prior.As(&priorValues); err != nil {
+		s.t.Fatal(err)
+	}
+	if err := config.As(&values); err != nil {
+		s.t.Fatal(err)
+	}
+
+	for _, a := range schema.Block.Attributes {
+		if a.Computed && values[a.Name].IsNull() {
+			values[a.Name] = priorValues[a.Name]
+		}
+	}
+
+	return tftypes.NewValue(config.Type(), values)
+}
+
+// plan validates attrs, the configuration of a resource, and plans the change
+// of its prior state to it. A nil attrs plans the destruction of the
+// resource.
+func (s *testServer) plan(typeName string, prior tftypes.Value, attrs map[string]interface{}) (config tftypes.Value, planned tftypes.Value, private []byte, diags []*tfprotov5.Diagnostic) {
+	s.t.Helper()
+
+	ctx := context.Background()
+	schema := s.resourceSchema(typeName)
+
+	config = s.config(schema, attrs)
+	if attrs != nil {
+		resp, err := s.server.ValidateResourceTypeConfig(ctx, &tfprotov5.ValidateResourceTypeConfigRequest{
+			TypeName: typeName,
+			Config:   s.dynamicValue(config),
+		})
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		diags = append(diags, resp.Diagnostics...)
+		if testHasError(diags) {
+			return config, prior, nil, diags
+		}
+	}
+
+	resp, err := s.server.PlanResourceChange(ctx, &tfprotov5.PlanResourceChangeRequest{
+		TypeName:         typeName,
+		PriorState:       s.dynamicValue(prior),
+		ProposedNewState: s.dynamicValue(s.proposedNewState(schema, prior, config)),
+		Config:           s.dynamicValue(config),
+	})
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	diags = append(diags, resp.Diagnostics...)
+	if testHasError(diags) {
+		return config, prior, nil, diags
+	}
+
+	return config, s.value(schema, resp.PlannedState), resp.PlannedPrivate, diags
+}
+
+// apply plans and applies the change of a resource from its prior state to
+// attrs, its configuration, or its destruction when attrs is nil. It returns
+// the new state, and the diagnostics of the plan and the apply.
+func (s *testServer) apply(typeName string, prior tftypes.Value, attrs map[string]interface{}) (tftypes.Value, []*tfprotov5.Diagnostic) {
+	s.t.Helper()
+
+	config, planned, private, diags := s.plan(typeName, prior, attrs)
+	if testHasError(diags) {
+		return prior, diags
+	}
+	if !prior.IsNull() && !planned.IsNull() && testCanonical(planned) == testCanonical(prior) {
+		return prior, diags
+	}
+
+	schema := s.resourceSchema(typeName)
+	resp, err := s.server.ApplyResourceChange(context.Background(), &tfprotov5.ApplyResourceChangeRequest{
+		TypeName:       typeName,
+		PriorState:     s.dynamicValue(prior),
+		PlannedState:   s.dynamicValue(planned),
+		Config:         s.dynamicValue(config),
+		PlannedPrivate: private,
+	})
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	diags = append(diags, resp.Diagnostics...)
+
+	state := s.value(schema, resp.NewState)
+	if !testHasError(resp.Diagnostics) {
+		s.checkApplied(typeName, planned, state)
+	}
+
+	return state, diags
+}
+
+// checkApplied fails the test, as Terraform fails the apply, when a known
+// value of the plan differs in the new state.
+func (s *testServer) checkApplied(typeName string, planned tftypes.Value, state tftypes.Value) {
+	s.t.Helper()
+
+	if planned.IsNull() || state.IsNull() {
+		if planned.IsNull() != state.IsNull() {
+			s.t.Errorf("%s: planned %s, applied %s", typeName, testCanonical(planned), testCanonical(state))
+		}
+		return
+	}
+
+	var plannedValues, values map[string]tftypes.Value
+	if err := planned.As(&plannedValues); err != nil {
+		s.t.Fatal(err)
+	}
+	if err := state.As(&values); err != nil {
+		s.t.Fatal(err)
+	}
+
+	for k, v := range plannedValues {
+		if !v.IsFullyKnown() {
+			continue
+		}
+
+		if testCanonical(v) != testCanonical(values[k]) {
+			s.t.Errorf("%s: planned %s = %s, applied %s", typeName, k, testCanonical(v), testCanonical(values[k]))
+		}
+	}
+}
+
+// create applies attrs to a resource which does not exist yet.
+func (s *testServer) create(typeName string, attrs map[string]interface{}) (tftypes.Value, []*tfprotov5.Diagnostic) {
+	s.t.Helper()
+
+	return s.apply(typeName, tftypes.NewValue(s.resourceSchema(typeName).ValueType(), nil), attrs)
+}
+
+// destroy plans and applies the destruction of a resource.
+func (s *testServer) destroy(typeName string, state tftypes.Value) (tftypes.Value, []*tfprotov5.Diagnostic) {
+	s.t.Helper()
+
+	return s.apply(typeName, state, nil)
+}
+
+// read refreshes the state of a resource.
+func (s *testServer) read(typeName string, state tftypes.Value) (tftypes.Value, []*tfprotov5.Diagnostic) {
+	s.t.Helper()
+
+	resp, err := s.server.ReadResource(context.Background(), &tfprotov5.ReadResourceRequest{
+		TypeName:     typeName,
+		CurrentState: s.dynamicValue(state),
+	})
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	return s.value(s.resourceSchema(typeName), resp.NewState), resp.Diagnostics
+}
+
+// checkNoChanges refreshes the state of a resource, and fails the test when
+// planning attrs, its configuration, proposes a change. It returns the
+// refreshed state.
+func (s *testServer) checkNoChanges(typeName string, state tftypes.Value, attrs map[string]interface{}) tftypes.Value {
+	s.t.Helper()
+
+	state, diags := s.read(typeName, state)
+	s.fatalDiagnostics("refreshing "+typeName, diags)
+
+	_, planned, _, diags := s.plan(typeName, state, attrs)
+	s.fatalDiagnostics("planning "+typeName, diags)
+
+	want, got := testNative(state).(map[string]interface{}), testNative(planned).(map[string]interface{})
+	for k := range want {
+		if !reflect.DeepEqual(got[k], want[k]) {
+			s.t.Errorf("%s: the plan changes %s from %v to %v", typeName, k, want[k], got[k])
+		}
+	}
+
+	return state
+}
+
+// importState imports the resource id, and reads it as Terraform does.
+func (s *testServer) importState(typeName string, id string) (tftypes.Value, []*tfprotov5.Diagnostic) {
+	s.t.Helper()
+
+	resp, err := s.server.ImportResourceState(context.Background(), &tfprotov5.ImportResourceStateRequest{
+		TypeName: typeName,
+		ID:       id,
+	})
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	if testHasError(resp.Diagnostics) {
+		return tftypes.NewValue(s.resourceSchema(typeName).ValueType(), nil), resp.Diagnostics
+	}
+	if len(resp.ImportedResources) != 1 {
+		s.t.Fatalf("imported %d resources, want 1", len(resp.ImportedResources))
+	}
+
+	state, diags := s.read(typeName, s.value(s.resourceSchema(typeName), resp.ImportedResources[0].State))
+
+	return state, append(resp.Diagnostics, diags...)
+}
+
+// upgrade upgrades rawState, the JSON state of a resource stored with the
+// schema version.
+func (s *testServer) upgrade(typeName string, version int64, rawState string) (tftypes.Value, []*tfprotov5.Diagnostic) {
+	s.t.Helper()
+
+	resp, err := s.server.UpgradeResourceState(context.Background(), &tfprotov5.UpgradeResourceStateRequest{
+		TypeName: typeName,
+		Version:  version,
+		RawState: &tfprotov5.RawState{
+			JSON: []byte(rawState),
+		},
+	})
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	return s.value(s.resourceSchema(typeName), resp.UpgradedState), resp.Diagnostics
+}
+
+// readDataSource validates attrs, the configuration of a data source, and
+// reads it.
+func (s *testServer) readDataSource(typeName string, attrs map[string]interface{}) (tftypes.Value, []*tfprotov5.Diagnostic) {
+	s.t.Helper()
+
+	ctx := context.Background()
+	schema := s.dataSourceSchema(typeName)
+	config := s.config(schema, attrs)
+
+	vresp, err := s.server.ValidateDataSourceConfig(ctx, &tfprotov5.ValidateDataSourceConfigRequest{
+		TypeName: typeName,
+		Config:   s.dynamicValue(config),
+	})
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	if testHasError(vresp.Diagnostics) {
+		return tftypes.NewValue(schema.ValueType(), nil), vresp.Diagnostics
+	}
+
+	resp, err := s.server.ReadDataSource(ctx, &tfprotov5.ReadDataSourceRequest{
+		TypeName: typeName,
+		Config:   s.dynamicValue(config),
+	})
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	return s.value(schema, resp.State), append(vresp.Diagnostics, resp.Diagnostics...)
+}
+
+// testAttrs returns the attributes of state, converted by testNative.
+func testAttrs(state tftypes.Value) map[string]interface{} {
+	attrs, _ := testNative(state).(map[string]interface{})
+
+	return attrs
+}
+
+// testNative converts v to the Go values the tests compare: nil, string, int
+// or float64, bool, []interface{} and map[string]interface{}. The elements of
+// the sets are sorted, and the unknown values are testUnknown.
+func testNative(v tftypes.Value) interface{} {
+	if !v.IsKnown() {
+		return testUnknown
+	}
+	if v.IsNull() {
+		return nil
+	}
+
+	switch typ := v.Type(); {
+	case typ.Is(tftypes.String):
+		var s string
+		_ = v.As(&s)
+
+		return s
+	case typ.Is(tftypes.Number):
+		var f big.Float
+		_ = v.As(&f)
+		if i, acc := f.Int64(); acc == big.Exact {
+			return int(i)
+		}
+		n, _ := f.Float64()
+
+		return n
+	case typ.Is(tftypes.Bool):
+		var b bool
+		_ = v.As(&b)
+
+		return b
+	case typ.Is(tftypes.List{}), typ.Is(tftypes.Set{}), typ.Is(tftypes.Tuple{}):
+		var values []tftypes.Value
+		_ = v.As(&values)
+
+		natives := make([]interface{}, 0, len(values))
+		for _, e := range values {
+			natives = append(natives, testNative(e))
+		}
+		if typ.Is(tftypes.Set{}) {
+			sort.Slice(natives, func(i, j int) bool {
+				return fmt.Sprint(natives[i]) < fmt.Sprint(natives[j])
+			})
+		}
+
+		return natives
+	default:
+		var values map[string]tftypes.Value
+		_ = v.As(&values)
+
+		natives := make(map[string]interface{}, len(values))
+		for k, e := range values {
+			natives[k] = testNative(e)
+		}
+
+		return natives
+	}
+}
+
+// testCanonical formats v so that equal values, whatever the order of the
+// elements of their sets, have the same string.
+func testCanonical(v tftypes.Value) string {
+	return fmt.Sprintf("%#v", testNative(v))
+}
@@ -0,0 +1,360 @@
+package idefix
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/marty-macfly/goidefix/services/company"
+	"github.com/marty-macfly/goidefix/services/project"
+	"github.com/marty-macfly/terraform-provider-idefix/internal/cassette"
+	"github.com/marty-macfly/terraform-provider-idefix/internal/fakeidefix"
+)
+
+// testAccCassetteEnv selects whether the tests record their calls to Idefix or
+// replay them, see testIdefix.
+const testAccCassetteEnv = "IDEFIX_CASSETTE"
+
+// testAccCompanyEnv is the ID of the company the tests create their objects
+// in when recording, and the sweepers delete them from.
+const testAccCompanyEnv = "IDEFIX_TEST_COMPANY_ID"
+
+// testAccPrefix starts the name of the objects created by the tests, which the
+// sweepers delete.
+const testAccPrefix = "tf-acc"
+
+// testAccProtoV5ProviderFactories serves the provider the way main does.
+var testAccProtoV5ProviderFactories = map[string]func() (tfprotov5.ProviderServer, error){
+	"idefix": func() (tfprotov5.ProviderServer, error) {
+		factory, err := ProtoV5ProviderServerFactory(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		return factory(), nil
+	},
+}
+
+func TestMain(m *testing.M) {
+	resource.TestMain(m)
+}
+
+func TestProvider(t *testing.T) {
+	if err := Provider().InternalValidate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ProtoV5ProviderServerFactory(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSharedClient(t *testing.T) {
+	var logins int
+	testSetNewClient(t, func(context.Context, string, string, string) (*apiClient, error) {
+		logins++
+
+		return &apiClient{}, nil
+	})
+
+	ctx := context.Background()
+	clients := &sharedClient{}
+
+	a, _ := clients.get(ctx, "https://idefix.invalid", "login", "password")
+	b, _ := clients.get(ctx, "https://idefix.invalid", "login", "password")
+	if a != b || logins != 1 {
+		t.Errorf("got %d logins for the same settings, want 1", logins)
+	}
+
+	if _, err := clients.get(ctx, "https://idefix.invalid", "other", "password"); err != nil || logins != 2 {
+		t.Errorf("got %d logins after changing the settings, want 2", logins)
+	}
+}
+
+// testAccAPI is the Idefix API a test runs against.
+type testAccAPI struct {
+	URL      string
+	Login    string
+	Password string
+
+	// CompanyID and CompanyName identify the company the test works in.
+	CompanyID   int
+	CompanyName string
+
+	// Client is logged in the API, to set up and check the test objects.
+	Client *apiClient
+	// Fake is the fake Idefix, or nil when recording or replaying.
+	Fake *fakeidefix.Server
+}
+
+// testFakeIdefix returns a fake Idefix for the duration of the test, served
+// over HTTP so that the provider reaches it through the goidefix client. The
+// test fails if goidefix sends a request the fake has no route for.
+func testFakeIdefix(t *testing.T) *testAccAPI {
+	t.Helper()
+
+	fake := fakeidefix.NewServer()
+	t.Cleanup(func() {
+		fake.Close()
+		if unrouted := fake.Unrouted(); len(unrouted) > 0 {
+			t.Errorf("requests with no route in the fake Idefix: %v", unrouted)
+		}
+	})
+
+	api := &testAccAPI{
+		URL:         fake.URL,
+		Login:       fakeidefix.Login,
+		Password:    fakeidefix.Password,
+		CompanyID:   fakeidefix.CompanyID,
+		CompanyName: fakeidefix.CompanyName,
+		Fake:        fake,
+	}
+	api.Client = testClient(t, api)
+
+	return api
+}
+
+// testSetNewClient replaces newClient for the duration of the test.
+func testSetNewClient(t *testing.T, f func(context.Context, string, string, string) (*apiClient, error)) {
+	newClient = f
+	t.Cleanup(func() {
+		newClient = newIdefixClient
+	})
+}
+
+// testClient returns a client logged in api.
+func testClient(t *testing.T, api *testAccAPI) *apiClient {
+	t.Helper()
+
+	client, err := newClient(context.Background(), api.URL, api.Login, api.Password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+// testIdefix returns the Idefix API for the duration of the test, selected by
+// IDEFIX_CASSETTE:
+//   - unset, the provider runs against a fake Idefix, see testFakeIdefix;
+//   - "record", the HTTP traffic of the provider with the real Idefix
+//     configured by the IDEFIX_URL, IDEFIX_LOGIN, IDEFIX_PASSWORD and
+//     IDEFIX_TEST_COMPANY_ID environment variables is recorded to
+//     testdata/cassettes;
+//   - "replay", the requests are answered from testdata/cassettes, and the
+//     test is skipped when it has not been recorded.
+//
+// When recording or replaying, the provider is pointed at a proxy going
+// through the recorder, so it runs with the goidefix client as it would
+// against Idefix.
+func testIdefix(t *testing.T) *testAccAPI {
+	t.Helper()
+
+	mode := cassette.Mode(os.Getenv(testAccCassetteEnv))
+
+	switch mode {
+	case "":
+		return testFakeIdefix(t)
+	case cassette.ModeRecord:
+		for _, env := range []string{"IDEFIX_URL", "IDEFIX_LOGIN", "IDEFIX_PASSWORD", testAccCompanyEnv} {
+			if os.Getenv(env) == "" {
+				t.Fatalf("%s must be set to record the tests against a real Idefix", env)
+			}
+		}
+
+		target, err := url.Parse(os.Getenv("IDEFIX_URL"))
+		if err != nil {
+			t.Fatalf("invalid IDEFIX_URL: %s", err)
+		}
+		companyID, err := strconv.Atoi(os.Getenv(testAccCompanyEnv))
+		if err != nil {
+			t.Fatalf("invalid %s: %s", testAccCompanyEnv, err)
+		}
+
+		rec := testRecorder(t, mode)
+		rec.SetVariable("company_id", strconv.Itoa(companyID))
+		rec.AddSecret(target.Host)
+		rec.AddSecret(os.Getenv("IDEFIX_LOGIN"))
+		rec.AddSecret(os.Getenv("IDEFIX_PASSWORD"))
+
+		proxy := httptest.NewServer(rec.Handler(target))
+		t.Cleanup(proxy.Close)
+
+		api := &testAccAPI{
+			URL:       proxy.URL,
+			Login:     os.Getenv("IDEFIX_LOGIN"),
+			Password:  os.Getenv("IDEFIX_PASSWORD"),
+			CompanyID: companyID,
+		}
+		api.Client = testClient(t, api)
+		api.CompanyName = testCompanyName(t, api)
+
+		return api
+	case cassette.ModeReplay:
+		rec := testRecorder(t, mode)
+
+		companyID, err := strconv.Atoi(rec.Variable("company_id"))
+		if err != nil {
+			t.Fatalf("invalid company_id in cassette: %s", err)
+		}
+
+		proxy := httptest.NewServer(rec.Handler(nil))
+		t.Cleanup(proxy.Close)
+
+		// The credentials were scrubbed from the cassette, which matches
+		// them whatever they are.
+		api := &testAccAPI{
+			URL:       proxy.URL,
+			Login:     cassette.Redacted,
+			Password:  cassette.Redacted,
+			CompanyID: companyID,
+		}
+		api.Client = testClient(t, api)
+		api.CompanyName = testCompanyName(t, api)
+
+		return api
+	default:
+		t.Fatalf("invalid %s %q, must be %q or %q", testAccCassetteEnv, mode, cassette.ModeRecord, cassette.ModeReplay)
+	}
+
+	return nil
+}
+
+// testAccIdefix returns the Idefix API of an acceptance test, see testIdefix.
+func testAccIdefix(t *testing.T) *testAccAPI {
+	t.Helper()
+
+	if os.Getenv(resource.EnvTfAcc) == "" {
+		t.Skipf("Acceptance tests skipped unless env '%s' set", resource.EnvTfAcc)
+	}
+
+	return testIdefix(t)
+}
+
+// testCompanyName reads the name of the company the test works in.
+func testCompanyName(t *testing.T, api *testAccAPI) string {
+	t.Helper()
+
+	c, err := api.Client.Company.Read(context.Background(), &company.ReadRequest{
+		ID: strconv.Itoa(api.CompanyID),
+	})
+	if err != nil {
+		t.Fatalf("reading company %d: %s", api.CompanyID, err)
+	}
+
+	return c.Name
+}
+
+// testRecorder returns the recorder of the cassette of the test, saved at its
+// end.
+func testRecorder(t *testing.T, mode cassette.Mode) *cassette.Recorder {
+	t.Helper()
+
+	path := filepath.Join("testdata", "cassettes", strings.ReplaceAll(t.Name(), "/", "_")+".json")
+	if _, err := os.Stat(path); mode == cassette.ModeReplay && os.IsNotExist(err) {
+		t.Skipf("no cassette recorded at %s", path)
+	}
+
+	rec, err := cassette.New(path, mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if err := rec.Save(); err != nil {
+			t.Errorf("saving cassette %s: %s", path, err)
+		}
+	})
+
+	return rec
+}
+
+// testAccConfig prepends to config the provider block pointing to api.
+func testAccConfig(api *testAccAPI, config string) string {
+	return fmt.Sprintf(`
+provider "idefix" {
+  url      = %q
+  login    = %q
+  password = %q
+}
+`, api.URL, api.Login, api.Password) + config
+}
+
+// createProject creates a project for the test, deleted at its end.
+func (api *testAccAPI) createProject(t *testing.T, req project.CreateRequest) string {
+	t.Helper()
+
+	ctx := context.Background()
+
+	if req.CompanyID == 0 {
+		req.CompanyID = api.CompanyID
+	}
+
+	resp, err := api.Client.Project.Create(ctx, &req)
+	if err != nil {
+		t.Fatalf("creating project %s: %s", req.Name, err)
+	}
+
+	t.Cleanup(func() {
+		_, err := api.Client.Project.Delete(ctx, &project.DeleteRequest{
+			ID: resp.ID,
+		})
+		if err != nil && !isNotFound(err) {
+			t.Errorf("deleting project %s: %s", resp.ID, err)
+		}
+	})
+
+	return resp.ID
+}
+
+// sweeperClient returns a client logged in the Idefix configured by the
+// IDEFIX_URL, IDEFIX_LOGIN and IDEFIX_PASSWORD environment variables, and the
+// ID of the company given by IDEFIX_TEST_COMPANY_ID, the only one the
+// sweepers delete from.
+func sweeperClient() (*apiClient, int, error) {
+	for _, env := range []string{"IDEFIX_LOGIN", "IDEFIX_PASSWORD", testAccCompanyEnv} {
+		if os.Getenv(env) == "" {
+			return nil, 0, fmt.Errorf("%s must be set to run the sweepers", env)
+		}
+	}
+
+	companyID, err := strconv.Atoi(os.Getenv(testAccCompanyEnv))
+	if err != nil || companyID <= 0 {
+		return nil, 0, fmt.Errorf("invalid %s %q, it must be the ID of the company of the tests", testAccCompanyEnv, os.Getenv(testAccCompanyEnv))
+	}
+
+	client, err := newClient(context.Background(), os.Getenv("IDEFIX_URL"), os.Getenv("IDEFIX_LOGIN"), os.Getenv("IDEFIX_PASSWORD"))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return client, companyID, nil
+}
+
+func TestSweeperClient_company(t *testing.T) {
+	fake := fakeidefix.NewServer()
+	t.Cleanup(fake.Close)
+	t.Setenv("IDEFIX_URL", fake.URL)
+	t.Setenv("IDEFIX_LOGIN", fakeidefix.Login)
+	t.Setenv("IDEFIX_PASSWORD", fakeidefix.Password)
+
+	for _, companyID := range []string{"", "0", "abc"} {
+		t.Setenv(testAccCompanyEnv, companyID)
+		if _, _, err := sweeperClient(); err == nil {
+			t.Errorf("%s=%q: the sweepers would run", testAccCompanyEnv, companyID)
+		}
+	}
+
+	t.Setenv(testAccCompanyEnv, "42")
+	if _, companyID, err := sweeperClient(); err != nil || companyID != 42 {
+		t.Errorf("got %d, %v, want 42", companyID, err)
+	}
+}
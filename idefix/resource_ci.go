@@ -2,631 +2,1195 @@ package idefix
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"sort"
 	"strconv"
 	"strings"
-
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/marty-macfly/goidefix"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/marty-macfly/goidefix/services/ci"
+	"github.com/marty-macfly/goidefix/services/company"
 	"github.com/marty-macfly/goidefix/services/equipment"
 	"github.com/marty-macfly/goidefix/services/monitoring"
+	"github.com/marty-macfly/goidefix/services/project"
+)
+
+var (
+	_ resource.ResourceWithConfigure      = &ciResource{}
+	_ resource.ResourceWithValidateConfig = &ciResource{}
+	_ resource.ResourceWithModifyPlan     = &ciResource{}
+	_ resource.ResourceWithImportState    = &ciResource{}
+	_ resource.ResourceWithUpgradeState   = &ciResource{}
+)
+
+// The timeouts of the operations on a CI, when the timeouts block does not
+// set them.
+const (
+	ciCreateTimeout = 20 * time.Minute
+	ciReadTimeout   = 5 * time.Minute
+	ciUpdateTimeout = 20 * time.Minute
+	ciDeleteTimeout = 10 * time.Minute
+)
+
+type ciResource struct {
+	client *apiClient
+}
+
+type ciResourceModel struct {
+	ID                 types.String `tfsdk:"id"`
+	Name               types.String `tfsdk:"name"`
+	TypeID             types.Int64  `tfsdk:"type_id"`
+	CompanyID          types.Int64  `tfsdk:"company_id"`
+	ProjectIDs         types.Set    `tfsdk:"project_ids"`
+	OutsourcingName    types.String `tfsdk:"outsourcing_name"`
+	ServiceLevelID     types.Int64  `tfsdk:"service_level_id"`
+	Team               types.String `tfsdk:"team"`
+	IsOwnerLBN         types.Bool   `tfsdk:"is_owner_lbn"`
+	Comment            types.String `tfsdk:"comment"`
+	RollbackOnFailure  types.Bool   `tfsdk:"rollback_on_failure"`
+	DeletionProtection types.Bool   `tfsdk:"deletion_protection"`
+	ServiceAT          types.Set    `tfsdk:"service_at"`
+	KeyDates           types.Set    `tfsdk:"key_dates"`
+	ServiceCloud       types.Set    `tfsdk:"service_cloud"`
+	Timeouts           types.Object `tfsdk:"timeouts"`
+}
+
+type ciServiceATModel struct {
+	RequiredServices types.Set `tfsdk:"required_services"`
+	MonitoringTool   types.Set `tfsdk:"monitoring_tool"`
+}
+
+type ciKeyDatesModel struct {
+	EnvironmentIDs   types.Set `tfsdk:"environment_ids"`
+	EnvironmentNames types.Set `tfsdk:"environment_names"`
+	FunctionIDs      types.Set `tfsdk:"function_ids"`
+	FunctionNames    types.Set `tfsdk:"function_names"`
+}
+
+type ciServiceCloudModel struct {
+	SubscriptionID types.Int64 `tfsdk:"subscription_id"`
+	ProductID      types.Int64 `tfsdk:"product_id"`
+	RegionID       types.Int64 `tfsdk:"region_id"`
+}
+
+// The types of the elements of the blocks of a CI.
+var (
+	ciServiceATType = types.ObjectType{
+		AttrTypes: map[string]attr.Type{
+			"required_services": types.SetType{ElemType: types.Int64Type},
+			"monitoring_tool":   types.SetType{ElemType: types.Int64Type},
+		},
+	}
+	ciKeyDatesType = types.ObjectType{
+		AttrTypes: map[string]attr.Type{
+			"environment_ids":   types.SetType{ElemType: types.Int64Type},
+			"environment_names": types.SetType{ElemType: types.StringType},
+			"function_ids":      types.SetType{ElemType: types.Int64Type},
+			"function_names":    types.SetType{ElemType: types.StringType},
+		},
+	}
+	ciServiceCloudType = types.ObjectType{
+		AttrTypes: map[string]attr.Type{
+			"subscription_id": types.Int64Type,
+			"product_id":      types.Int64Type,
+			"region_id":       types.Int64Type,
+		},
+	}
 )
 
-func resourceCI() *schema.Resource {
-	return &schema.Resource{
-		CreateContext: resourceCICreate,
-		ReadContext:   resourceCIRead,
-		UpdateContext: resourceCIUpdate,
-		DeleteContext: resourceCIDelete,
-		Description:   "Manages CI.",
-		Schema: map[string]*schema.Schema{
-			"id": {
-				Type:        schema.TypeString,
+func newCIResource() resource.Resource {
+	return &ciResource{}
+}
+
+func (r *ciResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_ci"
+}
+
+// Schema returns the schema of the CI resource. Its version 1 is the schema
+// of the SDK resource, which has the same attributes and blocks but stored
+// empty lists and comments where the framework stores null.
+func (r *ciResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	ids := func(description string) schema.SetAttribute {
+		return schema.SetAttribute{
+			Optional:    true,
+			ElementType: types.Int64Type,
+			Description: description,
+		}
+	}
+	names := func(description string) schema.SetAttribute {
+		return schema.SetAttribute{
+			Optional:    true,
+			ElementType: types.StringType,
+			Description: description,
+		}
+	}
+
+	resp.Schema = schema.Schema{
+		Description: "Manages CI.",
+		Version:     2,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
 				Computed:    true,
 				Description: "The ID of this resource.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
 			},
-			"name": {
-				Type:        schema.TypeString,
+			"name": schema.StringAttribute{
 				Required:    true,
 				Description: "The name of this CI.",
 			},
-			"type_id": {
-				Type:        schema.TypeInt,
+			"type_id": schema.Int64Attribute{
 				Optional:    true,
-				Default:     41,
-				Description: "The type of the CI.",
+				Computed:    true,
+				Description: "The type of the CI. Defaults to `41`.",
+				PlanModifiers: []planmodifier.Int64{
+					defaultInt64(41),
+				},
 			},
-			"company_id": {
-				Type:        schema.TypeInt,
+			"company_id": schema.Int64Attribute{
 				Required:    true,
 				Description: "The company ID associated to the CI.",
 			},
-			"project_ids": {
-				Type:        schema.TypeList,
+			"project_ids": schema.SetAttribute{
 				Required:    true,
+				ElementType: types.Int64Type,
 				Description: "The projects associated to the CI.",
-				Elem: &schema.Schema{
-					Type: schema.TypeInt,
-				},
 			},
-			"outsourcing_name": {
-				Type:        schema.TypeString,
+			"outsourcing_name": schema.StringAttribute{
 				Optional:    true,
-				Default:     "0 - Non-défini",
-				Description: "The Outsourcing level name.",
+				Computed:    true,
+				Description: "The Outsourcing level name. Defaults to `0 - Non-défini`.",
+				PlanModifiers: []planmodifier.String{
+					defaultString("0 - Non-défini"),
+				},
 			},
-			"service_level_id": {
-				Type:        schema.TypeInt,
+			"service_level_id": schema.Int64Attribute{
 				Optional:    true,
-				Default:     100000080,
-				Description: "The Level of the service.",
+				Computed:    true,
+				Description: "The Level of the service. Defaults to `100000080`.",
+				PlanModifiers: []planmodifier.Int64{
+					defaultInt64(100000080),
+				},
 			},
-			"team": {
-				Type:        schema.TypeString,
+			"team": schema.StringAttribute{
 				Optional:    true,
-				Default:     "Unix",
-				Description: "The team in charge.",
+				Computed:    true,
+				Description: "The team in charge. Defaults to `Unix`.",
+				PlanModifiers: []planmodifier.String{
+					defaultString("Unix"),
+				},
 			},
-			"is_owner_lbn": {
-				Type:        schema.TypeBool,
+			"is_owner_lbn": schema.BoolAttribute{
 				Optional:    true,
-				Default:     true,
-				Description: "The owner of the CI.",
+				Computed:    true,
+				Description: "The owner of the CI. Defaults to `true`.",
+				PlanModifiers: []planmodifier.Bool{
+					defaultBool(true),
+				},
 			},
-			"comment": {
-				Type:        schema.TypeString,
+			"comment": schema.StringAttribute{
 				Optional:    true,
 				Description: "Comment.",
 			},
-			"service_at": {
-				Type:        schema.TypeSet,
+			"rollback_on_failure": schema.BoolAttribute{
 				Optional:    true,
 				Computed:    true,
+				Description: "Whether the CI is deleted when one of the calls following its creation fails. Otherwise the CI is kept and marked as tainted. Defaults to `false`.",
+				PlanModifiers: []planmodifier.Bool{
+					defaultBool(false),
+				},
+			},
+			"deletion_protection": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "Whether Terraform will be prevented from destroying the CI. When set to `true`, a `terraform destroy` or a replacement of the CI will fail until it is set to `false` and applied. Defaults to `false`.",
+				PlanModifiers: []planmodifier.Bool{
+					defaultBool(false),
+				},
+			},
+		},
+		// The blocks are only read back from Idefix when they are set, as
+		// Terraform requires them to match the configuration: a block left
+		// out of the configuration is neither applied nor refreshed.
+		Blocks: map[string]schema.Block{
+			"service_at": schema.SetNestedBlock{
 				Description: "Services AT.",
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"required_services": {
-							Type:        schema.TypeList,
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"required_services": schema.SetAttribute{
 							Required:    true,
-							Description: "Required Services IDs.",
-							Elem: &schema.Schema{
-								Type: schema.TypeInt,
-							},
+							ElementType: types.Int64Type,
+							Description: "Required Services IDs, see the `idefix_required_services` data source.",
 						},
-						"monitoring_tool": {
-							Type:        schema.TypeList,
+						"monitoring_tool": schema.SetAttribute{
 							Required:    true,
-							Description: "Monitoring Tool IDs.",
-							Elem: &schema.Schema{
-								Type: schema.TypeInt,
-							},
+							ElementType: types.Int64Type,
+							Description: "Monitoring Tool IDs, see the `idefix_monitoring_tools` data source.",
 						},
 					},
 				},
 			},
-			"key_dates": {
-				Type:        schema.TypeSet,
-				Optional:    true,
-				Computed:    true,
+			"key_dates": schema.SetNestedBlock{
 				Description: "Use And Key Date.",
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"environment_ids": {
-							Type:        schema.TypeList,
-							Required:    true,
-							Description: "Environments of the CI.",
-							Elem: &schema.Schema{
-								Type: schema.TypeInt,
-							},
-						},
-						"function_ids": {
-							Type:        schema.TypeList,
-							Required:    true,
-							Description: "Functions of the CI.",
-							Elem: &schema.Schema{
-								Type: schema.TypeInt,
-							},
-						},
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"environment_ids":   ids("Environments of the CI. Conflicts with `environment_names`."),
+						"environment_names": names("Names of the environments of the CI, see the `idefix_environments` data source. Conflicts with `environment_ids`."),
+						"function_ids":      ids("Functions of the CI. Conflicts with `function_names`."),
+						"function_names":    names("Names of the functions of the CI, see the `idefix_functions` data source. Conflicts with `function_ids`."),
 					},
 				},
 			},
-			"service_cloud": {
-				Type:        schema.TypeSet,
-				Optional:    true,
-				Computed:    true,
+			"service_cloud": schema.SetNestedBlock{
 				Description: "Service Cloud.",
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"subscription_id": {
-							Type:        schema.TypeInt,
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"subscription_id": schema.Int64Attribute{
 							Required:    true,
-							Description: "The Subscription ID of the CI.",
+							Description: "The Subscription ID of the CI, see the `idefix_cloud_subscriptions` data source.",
 						},
-						"product_id": {
-							Type:        schema.TypeInt,
+						"product_id": schema.Int64Attribute{
 							Required:    true,
-							Description: "The Product ID of the CI.",
+							Description: "The Product ID of the CI, see the `idefix_cloud_products` data source.",
 						},
-						"region_id": {
-							Type:        schema.TypeInt,
+						"region_id": schema.Int64Attribute{
 							Required:    true,
-							Description: "The Region ID of the CI.",
+							Description: "The Region ID of the CI, see the `idefix_cloud_regions` data source.",
 						},
 					},
 				},
 			},
-		},
-		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
+			"timeouts": timeoutsBlock(),
 		},
 	}
 }
 
-func resourceCICreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*goidefix.Idefix)
-
-	ids := d.Get("project_ids").([]interface{})
-	projectIDs := make([]int, len(ids))
-	for i := range ids {
-		projectIDs[i] = ids[i].(int)
+func (r *ciResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
 	}
 
-	cir, err := client.CI.Create(ctx, &ci.CreateRequest{
-		Name:            d.Get("name").(string),
-		TypeID:          d.Get("type_id").(int),
-		CompanyID:       d.Get("company_id").(int),
-		ProjectIDs:      projectIDs,
-		OutSourcingName: d.Get("outsourcing_name").(string),
-		ServiceLevelID:  d.Get("service_level_id").(int),
-		Team:            d.Get("team").(string),
-		IsOwnerLBN:      d.Get("is_owner_lbn").(bool),
-		Comment:         d.Get("comment").(string),
-	})
-	if err != nil {
-		return diag.FromErr(err)
+	client, ok := req.ProviderData.(*apiClient)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected resource configure type", fmt.Sprintf("Expected *apiClient, got: %T.", req.ProviderData))
+		return
 	}
 
-	if v, ok := d.GetOk("service_cloud"); ok && v.(*schema.Set).Len() > 0 {
-		for _, serviceCloudSet := range v.(*schema.Set).List() {
-			var subscriptionId, productID int
-			var regionID string
+	r.client = client
+}
 
-			serviceCloud, ok := serviceCloudSet.(map[string]interface{})
+// ValidateConfig checks that each key_dates block sets its environments and
+// functions, either by ID or by name. A list which is not known yet is not
+// taken for an unset one.
+func (r *ciResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var keyDates types.Set
 
-			if !ok {
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("key_dates"), &keyDates)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	blocks, diags := setBlocks[ciKeyDatesModel](ctx, keyDates)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// As key_dates is a set, the errors do not name a block by its index.
+	for _, block := range blocks {
+		for _, kind := range []struct {
+			name  string
+			ids   types.Set
+			names types.Set
+		}{
+			{"environment", block.EnvironmentIDs, block.EnvironmentNames},
+			{"function", block.FunctionIDs, block.FunctionNames},
+		} {
+			if kind.ids.IsUnknown() || kind.names.IsUnknown() {
 				continue
 			}
 
-			if v, ok := serviceCloud["subscription_id"].(int); ok && v > 0 {
-				subscriptionId = v
+			switch {
+			case kind.ids.IsNull() && kind.names.IsNull():
+				resp.Diagnostics.AddAttributeError(path.Root("key_dates"), "Invalid key_dates block", fmt.Sprintf("Each block must set one of %s_ids or %s_names.", kind.name, kind.name))
+			case !kind.ids.IsNull() && !kind.names.IsNull():
+				resp.Diagnostics.AddAttributeError(path.Root("key_dates"), "Invalid key_dates block", fmt.Sprintf("A block sets both %s_ids and %s_names, only one of them can be set.", kind.name, kind.name))
 			}
+		}
+	}
+}
 
-			if v, ok := serviceCloud["product_id"].(int); ok && v > 0 {
-				productID = v
-			}
+// ModifyPlan checks that the catalog entries, company, projects, required
+// services and monitoring tools of the CI exist in Idefix, when they change.
+func (r *ciResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
 
-			if v, ok := serviceCloud["region_id"].(int); ok && v > 0 {
-				regionID = strconv.Itoa(v)
+	var plan, state ciResourceModel
 
-			}
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if !req.State.Raw.IsNull() {
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-			_, err := client.CI.UpdateServiceCloud(ctx, &ci.UpdateServiceCloudRequest{
-				ID:             cir.ID,
-				SubscriptionID: subscriptionId,
-				ProductID:      productID,
-				RegionID:       regionID,
-			})
-			if err != nil {
-				return diag.FromErr(err)
-			}
+	// A value is checked when it is known, and set on creation or changed
+	// since the state.
+	creating := req.State.Raw.IsNull()
+	changed := func(plan attr.Value, state attr.Value) bool {
+		return !plan.IsUnknown() && !plan.IsNull() && (creating || !plan.Equal(state))
+	}
+
+	resp.Diagnostics.Append(ciValidateCatalog(ctx, r.client, plan, state, changed)...)
+
+	if changed(plan.CompanyID, state.CompanyID) {
+		resp.Diagnostics.Append(ciValidateCompany(ctx, r.client, int(plan.CompanyID.ValueInt64()))...)
+	}
+
+	if changed(plan.ProjectIDs, state.ProjectIDs) {
+		resp.Diagnostics.Append(ciValidateProjects(ctx, r.client, expandIDs(plan.ProjectIDs))...)
+	}
+
+	if changed(plan.ServiceAT, state.ServiceAT) {
+		resp.Diagnostics.Append(ciValidateServiceAT(ctx, r.client, plan.ServiceAT)...)
+	}
+}
+
+// ciValidateCatalog checks that the type, service level and outsourcing level
+// of the CI exist in Idefix.
+func ciValidateCatalog(ctx context.Context, client *apiClient, plan ciResourceModel, state ciResourceModel, changed func(attr.Value, attr.Value) bool) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	ids := []struct {
+		attr  string
+		item  string
+		name  string
+		list  func(context.Context, *apiClient) ([]catalogItem, error)
+		plan  types.Int64
+		state types.Int64
+	}{
+		{"type_id", "CI type", "ci_types", listCITypes, plan.TypeID, state.TypeID},
+		{"service_level_id", "service level", "service_levels", listServiceLevels, plan.ServiceLevelID, state.ServiceLevelID},
+	}
+	for _, c := range ids {
+		if !changed(c.plan, c.state) {
+			continue
+		}
+
+		items, err := cachedCatalog(ctx, client, c.name, c.list)
+		if err != nil {
+			diags.AddAttributeError(path.Root(c.attr), "Unable to list the "+c.name, err.Error())
+			continue
+		}
+
+		id := int(c.plan.ValueInt64())
+		if !catalogHasID(items, id) {
+			diags.AddAttributeError(path.Root(c.attr), "Invalid attribute value", fmt.Sprintf("unknown %s ID %d", c.item, id))
 		}
 	}
 
-	if v, ok := d.GetOk("key_dates"); ok && v.(*schema.Set).Len() > 0 {
-		for _, keyDatesSet := range v.(*schema.Set).List() {
-			var envIDs, funcIDs []int
+	if changed(plan.OutsourcingName, state.OutsourcingName) {
+		items, err := cachedCatalog(ctx, client, "outsourcing_levels", listOutsourcingLevels)
+		if err != nil {
+			diags.AddAttributeError(path.Root("outsourcing_name"), "Unable to list the outsourcing_levels", err.Error())
+			return diags
+		}
 
-			keyDates, ok := keyDatesSet.(map[string]interface{})
+		name := plan.OutsourcingName.ValueString()
+		if !catalogHasName(items, name) {
+			diags.AddAttributeError(path.Root("outsourcing_name"), "Invalid attribute value", fmt.Sprintf("unknown outsourcing level %q", name))
+		}
+	}
 
-			if !ok {
-				continue
-			}
+	return diags
+}
 
-			if v, ok := keyDates["environment_ids"].([]interface{}); ok && len(v) > 0 {
-				for i := range v {
-					envIDs = append(envIDs, v[i].(int))
-				}
-			}
+// ciValidateCompany checks that the company of the CI exists in Idefix.
+func ciValidateCompany(ctx context.Context, client *apiClient, companyID int) diag.Diagnostics {
+	var diags diag.Diagnostics
 
-			if v, ok := keyDates["function_ids"].([]interface{}); ok && len(v) > 0 {
-				for i := range v {
-					funcIDs = append(funcIDs, v[i].(int))
-				}
-			}
+	id := strconv.Itoa(companyID)
+	company, err := client.Company.Read(ctx, &company.ReadRequest{
+		ID: id,
+	})
+	if isNotFound(err) || (err == nil && company == nil) {
+		diags.AddAttributeError(path.Root("company_id"), "Invalid attribute value", fmt.Sprintf("company %s not found", id))
+	} else if err != nil {
+		diags.AddAttributeError(path.Root("company_id"), "Unable to read company", fmt.Sprintf("reading company %s: %s", id, err))
+	}
 
-			_, err := client.CI.UpdateUseAndKeyDate(ctx, &ci.UpdateUseAndKeyDateRequest{
-				ID:             cir.ID,
-				EnvSelect:      0,
-				EnvironmentIDs: envIDs,
-				FuncSelect:     0,
-				FunctionIDs:    funcIDs,
-			})
-			if err != nil {
-				return diag.FromErr(err)
-			}
+	return diags
+}
+
+// ciValidateProjects checks that the projects of the CI exist in Idefix.
+func ciValidateProjects(ctx context.Context, client *apiClient, projectIDs []int) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	for _, v := range projectIDs {
+		id := strconv.Itoa(v)
+		project, err := client.Project.Read(ctx, &project.ReadRequest{
+			ID: id,
+		})
+		if isNotFound(err) || (err == nil && project == nil) {
+			diags.AddAttributeError(path.Root("project_ids"), "Invalid attribute value", fmt.Sprintf("project %s not found", id))
+			return diags
+		}
+		if err != nil {
+			diags.AddAttributeError(path.Root("project_ids"), "Unable to read project", fmt.Sprintf("reading project %s: %s", id, err))
+			return diags
 		}
 	}
 
-	if v, ok := d.GetOk("service_at"); ok && v.(*schema.Set).Len() > 0 {
-		for _, serviceATSet := range v.(*schema.Set).List() {
-			var requiredServices, monitoringTool string
+	return diags
+}
 
-			serviceAT, ok := serviceATSet.(map[string]interface{})
+// ciValidateServiceAT checks that the required services and monitoring tools
+// of the service_at blocks exist in Idefix.
+func ciValidateServiceAT(ctx context.Context, client *apiClient, serviceAT types.Set) diag.Diagnostics {
+	blocks, diags := setBlocks[ciServiceATModel](ctx, serviceAT)
+	if diags.HasError() {
+		return diags
+	}
 
-			if !ok {
+	// The blocks are a set, whose order is a hash of their content, so the
+	// errors name the attribute rather than an index which means nothing in
+	// the configuration.
+	for _, block := range blocks {
+		lists := []struct {
+			attr string
+			item string
+			name string
+			list func(context.Context, *apiClient) ([]catalogItem, error)
+			ids  types.Set
+		}{
+			{"required_services", "required service", "required_services", listRequiredServices, block.RequiredServices},
+			{"monitoring_tool", "monitoring tool", "monitoring_tools", listMonitoringTools, block.MonitoringTool},
+		}
+		for _, l := range lists {
+			ids := expandIDs(l.ids)
+			if len(ids) == 0 {
 				continue
 			}
 
-			if v, ok := serviceAT["required_services"].([]interface{}); ok && len(v) > 0 {
-				var ids []string
-				for i := range v {
-					ids = append(ids, strconv.Itoa(v[i].(int)))
-				}
-				requiredServices = strings.Join(ids, ",")
+			items, err := cachedCatalog(ctx, client, l.name, l.list)
+			if err != nil {
+				diags.AddAttributeError(path.Root("service_at"), "Unable to list the "+l.name, err.Error())
+				return diags
 			}
 
-			if v, ok := serviceAT["monitoring_tool"].([]interface{}); ok && len(v) > 0 {
-				var ids []string
-				for i := range v {
-					ids = append(ids, strconv.Itoa(v[i].(int)))
+			for _, id := range ids {
+				if !catalogHasID(items, id) {
+					diags.AddAttributeError(path.Root("service_at"), "Invalid attribute value", fmt.Sprintf("%s: unknown %s ID %d", l.attr, l.item, id))
+					return diags
 				}
-				monitoringTool = strings.Join(ids, ",")
-			}
-
-			_, err := client.Equipment.UpdateAT(ctx, &equipment.UpdateATRequest{
-				ID:               cir.ID,
-				RequiredServices: requiredServices,
-				MonitoringTool:   monitoringTool,
-				BackupComment:    "Asset PaaS",
-			})
-			if err != nil {
-				return diag.FromErr(err)
 			}
 		}
 	}
 
-	_, err = client.CI.UpdatePlatform(ctx, &ci.UpdatePlatformRequest{
-		ID: cir.ID,
+	return diags
+}
+
+func (r *ciResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan ciResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	createTimeout := timeout(plan.Timeouts, "create", ciCreateTimeout)
+	ctx, cancel := context.WithTimeout(ctx, createTimeout)
+	defer cancel()
+
+	cir, err := r.client.CI.Create(ctx, &ci.CreateRequest{
+		Name:            plan.Name.ValueString(),
+		TypeID:          int(plan.TypeID.ValueInt64()),
+		CompanyID:       int(plan.CompanyID.ValueInt64()),
+		ProjectIDs:      expandIDs(plan.ProjectIDs),
+		OutSourcingName: plan.OutsourcingName.ValueString(),
+		ServiceLevelID:  int(plan.ServiceLevelID.ValueInt64()),
+		Team:            plan.Team.ValueString(),
+		IsOwnerLBN:      plan.IsOwnerLBN.ValueBool(),
+		Comment:         plan.Comment.ValueString(),
 	})
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("Unable to create CI", stepError(ctx, err, "creating CI %s", plan.Name.ValueString()).Error())
+		return
 	}
 
-	d.SetId(cir.ID)
+	// The CI is saved in the state, and tainted, even if one of the following
+	// calls fails.
+	plan.ID = types.StringValue(cir.ID)
+
+	err = waitForCI(ctx, r.client, cir.ID, createTimeout)
+	if err == nil {
+		err = ciUpdateDetails(ctx, r.client, plan)
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to create CI", err.Error())
+
+		if plan.RollbackOnFailure.ValueBool() {
+			// The creation may have failed because its timeout elapsed, so
+			// the rollback gets its own deadline.
+			ctx, cancel := context.WithTimeout(context.Background(), timeout(plan.Timeouts, "delete", ciDeleteTimeout))
+			defer cancel()
+
+			rollbackErr := deleteCI(ctx, r.client, cir.ID)
+			if rollbackErr == nil {
+				return
+			}
+
+			resp.Diagnostics.AddError(fmt.Sprintf("Unable to roll back the creation of CI %s", cir.ID),
+				rollbackErr.Error()+"\n\nThe CI is kept in the state, marked as tainted, so that it is deleted by the next apply.")
+		}
+	}
 
-	return resourceCIRead(ctx, d, m)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
-func resourceCIRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	var diags diag.Diagnostics
+// waitForCI polls Idefix until the CI it has just created can be read back,
+// as the follow-up calls fail while the equipment is still being provisioned.
+func waitForCI(ctx context.Context, client *apiClient, id string, timeout time.Duration) error {
+	err := waitUntilReadable(ctx, id, timeout, func() (bool, error) {
+		cir, err := client.CI.Read(ctx, &ci.ReadRequest{
+			ID: id,
+		})
 
-	client := m.(*goidefix.Idefix)
-	cir, err := client.CI.Read(ctx, &ci.ReadRequest{
-		ID: d.Id(),
+		return cir != nil, err
 	})
-	if err != nil {
-		return diag.FromErr(err)
+
+	return stepError(ctx, err, "waiting for CI %s to be available", id)
+}
+
+// ciUpdateDetails pushes the service cloud, key dates and services AT of the
+// CI to Idefix, then updates its platform.
+func ciUpdateDetails(ctx context.Context, client *apiClient, m ciResourceModel) error {
+	id := m.ID.ValueString()
+
+	serviceClouds, diags := setBlocks[ciServiceCloudModel](ctx, m.ServiceCloud)
+	if err := diagsError(diags); err != nil {
+		return err
 	}
+	for _, serviceCloud := range serviceClouds {
+		var regionID string
+		if v := serviceCloud.RegionID.ValueInt64(); v > 0 {
+			regionID = strconv.FormatInt(v, 10)
+		}
 
-	var projectIDs []int
-	pids := strings.Split(cir.ProjectIDs, ",")
-	for _, pid := range pids {
-		if pid != "" {
-			id, err := strconv.Atoi(pid)
-			if err != nil {
-				return diag.FromErr(err)
-			}
+		_, err := client.CI.UpdateServiceCloud(ctx, &ci.UpdateServiceCloudRequest{
+			ID:             id,
+			SubscriptionID: int(serviceCloud.SubscriptionID.ValueInt64()),
+			ProductID:      int(serviceCloud.ProductID.ValueInt64()),
+			RegionID:       regionID,
+		})
+		if err != nil {
+			return stepError(ctx, err, "updating the service cloud of CI %s", id)
+		}
+	}
+
+	keyDates, diags := setBlocks[ciKeyDatesModel](ctx, m.KeyDates)
+	if err := diagsError(diags); err != nil {
+		return err
+	}
+	for _, keyDate := range keyDates {
+		envIDs, err := expandKeyDatesIDs(ctx, client, expandIDs(keyDate.EnvironmentIDs), expandNames(keyDate.EnvironmentNames), "environment", listEnvironments)
+		if err != nil {
+			return err
+		}
 
-			projectIDs = append(projectIDs, id)
+		funcIDs, err := expandKeyDatesIDs(ctx, client, expandIDs(keyDate.FunctionIDs), expandNames(keyDate.FunctionNames), "function", listFunctions)
+		if err != nil {
+			return err
+		}
+
+		_, err = client.CI.UpdateUseAndKeyDate(ctx, &ci.UpdateUseAndKeyDateRequest{
+			ID:             id,
+			EnvSelect:      0,
+			EnvironmentIDs: envIDs,
+			FuncSelect:     0,
+			FunctionIDs:    funcIDs,
+		})
+		if err != nil {
+			return stepError(ctx, err, "updating the key dates of CI %s", id)
 		}
 	}
 
-	typeID, err := strconv.Atoi(cir.TypeID)
-	if err != nil {
-		return diag.FromErr(err)
-	}
-
-	d.SetId(d.Id())
-	d.Set("name", cir.Name)
-	d.Set("company_id", cir.CompanyID)
-	d.Set("type_id", typeID)
-	d.Set("company_id", cir.CompanyID)
-	d.Set("project_ids", projectIDs)
-	d.Set("outsourcing_name", cir.OutSourcingName)
-	d.Set("service_level_id", cir.ServiceLevelID)
-	d.Set("team", cir.Team)
-	d.Set("is_owner_lbn", cir.IsOwnerLBN)
-	d.Set("comment", cir.Comment)
-
-	sc, err := client.CI.ReadServiceCloud(ctx, &ci.ReadServiceCloudRequest{
-		ID: d.Id(),
+	servicesAT, diags := setBlocks[ciServiceATModel](ctx, m.ServiceAT)
+	if err := diagsError(diags); err != nil {
+		return err
+	}
+	for _, serviceAT := range servicesAT {
+		_, err := client.Equipment.UpdateAT(ctx, &equipment.UpdateATRequest{
+			ID:               id,
+			RequiredServices: joinIDs(expandIDs(serviceAT.RequiredServices)),
+			MonitoringTool:   joinIDs(expandIDs(serviceAT.MonitoringTool)),
+			BackupComment:    "Asset PaaS",
+		})
+		if err != nil {
+			return stepError(ctx, err, "updating the services AT of CI %s", id)
+		}
+	}
+
+	_, err := client.CI.UpdatePlatform(ctx, &ci.UpdatePlatformRequest{
+		ID: id,
 	})
-	if err != nil {
-		return diag.FromErr(err)
+
+	return stepError(ctx, err, "updating the platform of CI %s", id)
+}
+
+func (r *ciResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state ciResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	serviceCloud := map[string]interface{}{}
-	serviceCloud["subscription_id"] = sc.SubscriptionID
-	serviceCloud["product_id"] = sc.ProductID
-	serviceCloud["region_id"], err = strconv.Atoi(sc.RegionID)
+	ctx, cancel := context.WithTimeout(ctx, timeout(state.Timeouts, "read", ciReadTimeout))
+	defer cancel()
+
+	id := state.ID.ValueString()
+
+	cir, err := r.client.CI.Read(ctx, &ci.ReadRequest{
+		ID: id,
+	})
+	if isNotFound(err) || (err == nil && cir == nil) {
+		// The CI has been deleted outside of Terraform, the next plan
+		// proposes to create it again.
+		log.Printf("[WARN] CI %s not found, removing from state", id)
+		resp.State.RemoveResource(ctx)
+
+		return
+	}
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("Unable to read CI", stepError(ctx, err, "reading CI %s", id).Error())
+		return
 	}
 
-	var serviceCloudSet []interface{}
-	serviceCloudSet = append(serviceCloudSet, serviceCloud)
-	d.Set("service_cloud", serviceCloudSet)
+	projectIDs, err := splitIDs(cir.ProjectIDs)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("project_ids"), "Unable to parse the project IDs of the CI", err.Error())
+		return
+	}
 
-	kd, err := client.CI.ReadUseAndKeyDate(ctx, &ci.ReadUseAndKeyDateRequest{
-		ID: d.Id(),
-	})
+	typeID, err := strconv.Atoi(cir.TypeID)
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddAttributeError(path.Root("type_id"), "Unable to parse the type of the CI", err.Error())
+		return
 	}
 
-	keyDate := map[string]interface{}{}
+	state.Name = types.StringValue(cir.Name)
+	state.TypeID = types.Int64Value(int64(typeID))
+	state.CompanyID = types.Int64Value(int64(cir.CompanyID))
+	state.ProjectIDs = int64Set(projectIDs)
+	state.OutsourcingName = types.StringValue(cir.OutSourcingName)
+	state.ServiceLevelID = types.Int64Value(int64(cir.ServiceLevelID))
+	state.Team = types.StringValue(cir.Team)
+	state.IsOwnerLBN = types.BoolValue(cir.IsOwnerLBN)
+	state.Comment = stringOrNull(cir.Comment, state.Comment)
+
+	// Only the CI itself tells whether it is gone. A missing or empty detail
+	// is read as no block, so that the CI is not dropped from the state and
+	// created again.
+	if refreshBlocks(state.ServiceCloud) {
+		sc, err := r.client.CI.ReadServiceCloud(ctx, &ci.ReadServiceCloudRequest{
+			ID: id,
+		})
+		if isNotFound(err) {
+			sc, err = nil, nil
+		}
+		if err != nil {
+			resp.Diagnostics.AddError("Unable to read CI", stepError(ctx, err, "reading the service cloud of CI %s", id).Error())
+			return
+		}
 
-	var envIDs []int
-	envIDsList := strings.Split(kd.EnvironmentIDs, ",")
-	if len(envIDsList) > 0 {
-		for _, envID := range envIDsList {
-			if envID == "" {
-				continue
+		serviceCloud := []ciServiceCloudModel{}
+		if sc != nil {
+			block, err := flattenServiceCloud(sc)
+			if err != nil {
+				resp.Diagnostics.AddAttributeError(path.Root("service_cloud"), "Unable to parse the service cloud of the CI", err.Error())
+				return
 			}
+			if block.SubscriptionID.ValueInt64() != 0 || block.ProductID.ValueInt64() != 0 || block.RegionID.ValueInt64() != 0 {
+				serviceCloud = append(serviceCloud, block)
+			}
+		}
+
+		var diags diag.Diagnostics
+		state.ServiceCloud, diags = types.SetValueFrom(ctx, ciServiceCloudType, serviceCloud)
+		resp.Diagnostics.Append(diags...)
+	}
 
-			id, err := strconv.Atoi(envID)
+	if refreshBlocks(state.KeyDates) {
+		kd, err := r.client.CI.ReadUseAndKeyDate(ctx, &ci.ReadUseAndKeyDateRequest{
+			ID: id,
+		})
+		if isNotFound(err) {
+			kd, err = nil, nil
+		}
+		if err != nil {
+			resp.Diagnostics.AddError("Unable to read CI", stepError(ctx, err, "reading the key dates of CI %s", id).Error())
+			return
+		}
+
+		keyDates := []ciKeyDatesModel{}
+		if kd != nil {
+			block, err := flattenKeyDates(kd)
 			if err != nil {
-				return diag.FromErr(err)
+				resp.Diagnostics.AddAttributeError(path.Root("key_dates"), "Unable to parse the key dates of the CI", err.Error())
+				return
+			}
+
+			prior, diags := setBlocks[ciKeyDatesModel](ctx, state.KeyDates)
+			resp.Diagnostics.Append(diags...)
+			if len(prior) > 0 {
+				if err := keyDatesByName(ctx, r.client, id, prior[0], &block); err != nil {
+					resp.Diagnostics.AddAttributeError(path.Root("key_dates"), "Unable to read the key dates of the CI", err.Error())
+					return
+				}
 			}
 
-			envIDs = append(envIDs, id)
+			if len(block.EnvironmentIDs.Elements()) > 0 || len(block.EnvironmentNames.Elements()) > 0 || len(block.FunctionIDs.Elements()) > 0 || len(block.FunctionNames.Elements()) > 0 {
+				keyDates = append(keyDates, block)
+			}
 		}
+
+		var diags diag.Diagnostics
+		state.KeyDates, diags = types.SetValueFrom(ctx, ciKeyDatesType, keyDates)
+		resp.Diagnostics.Append(diags...)
 	}
-	keyDate["environment_ids"] = envIDs
 
-	var funcIDs []int
-	funcIDsList := strings.Split(kd.FunctionIDs, ",")
-	if len(funcIDsList) > 0 {
-		for _, funcID := range funcIDsList {
-			if funcID == "" {
-				continue
-			}
+	if refreshBlocks(state.ServiceAT) {
+		at, err := r.client.Equipment.ReadAT(ctx, &equipment.ReadATRequest{
+			ID: id,
+		})
+		if isNotFound(err) {
+			at, err = nil, nil
+		}
+		if err != nil {
+			resp.Diagnostics.AddError("Unable to read CI", stepError(ctx, err, "reading the services AT of CI %s", id).Error())
+			return
+		}
 
-			id, err := strconv.Atoi(funcID)
+		serviceAT := []ciServiceATModel{}
+		if at != nil {
+			block, err := flattenServiceAT(at)
 			if err != nil {
-				return diag.FromErr(err)
+				resp.Diagnostics.AddAttributeError(path.Root("service_at"), "Unable to parse the services AT of the CI", err.Error())
+				return
 			}
+			if len(block.RequiredServices.Elements()) > 0 || len(block.MonitoringTool.Elements()) > 0 {
+				serviceAT = append(serviceAT, block)
+			}
+		}
 
-			funcIDs = append(funcIDs, id)
+		var diags diag.Diagnostics
+		state.ServiceAT, diags = types.SetValueFrom(ctx, ciServiceATType, serviceAT)
+		resp.Diagnostics.Append(diags...)
+	}
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+// refreshBlocks reports whether the blocks of the state are read back from
+// Idefix: when some are set, or when the CI is being imported.
+func refreshBlocks(blocks types.Set) bool {
+	return blocks.IsNull() || len(blocks.Elements()) > 0
+}
+
+func (r *ciResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state ciResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout(plan.Timeouts, "update", ciUpdateTimeout))
+	defer cancel()
+
+	if ciChanged(plan, state) {
+		_, err := r.client.CI.Update(ctx, &ci.UpdateRequest{
+			ID:              plan.ID.ValueString(),
+			Name:            plan.Name.ValueString(),
+			TypeID:          int(plan.TypeID.ValueInt64()),
+			CompanyID:       int(plan.CompanyID.ValueInt64()),
+			ProjectIDs:      expandIDs(plan.ProjectIDs),
+			OutSourcingName: plan.OutsourcingName.ValueString(),
+			ServiceLevelID:  int(plan.ServiceLevelID.ValueInt64()),
+			Team:            plan.Team.ValueString(),
+			IsOwnerLBN:      plan.IsOwnerLBN.ValueBool(),
+			Comment:         plan.Comment.ValueString(),
+		})
+		if err != nil {
+			resp.Diagnostics.AddError("Unable to update CI", stepError(ctx, err, "updating CI %s", plan.ID.ValueString()).Error())
+			return
+		}
+
+		if err := ciUpdateDetails(ctx, r.client, plan); err != nil {
+			resp.Diagnostics.AddError("Unable to update CI", err.Error())
+			return
 		}
 	}
-	keyDate["function_ids"] = funcIDs
 
-	var keyDatesSet []interface{}
-	keyDatesSet = append(keyDatesSet, keyDate)
-	d.Set("key_dates", keyDatesSet)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
 
-	at, err := client.Equipment.ReadAT(ctx, &equipment.ReadATRequest{
-		ID: d.Id(),
-	})
-	if err != nil {
-		return diag.FromErr(err)
+// ciChanged reports whether the CI planned differs from the one in the state,
+// leaving out the settings of the resource itself which are not sent to
+// Idefix.
+func ciChanged(plan ciResourceModel, state ciResourceModel) bool {
+	values := []struct{ plan, state attr.Value }{
+		{plan.Name, state.Name},
+		{plan.TypeID, state.TypeID},
+		{plan.CompanyID, state.CompanyID},
+		{plan.ProjectIDs, state.ProjectIDs},
+		{plan.OutsourcingName, state.OutsourcingName},
+		{plan.ServiceLevelID, state.ServiceLevelID},
+		{plan.Team, state.Team},
+		{plan.IsOwnerLBN, state.IsOwnerLBN},
+		{plan.Comment, state.Comment},
+		{plan.ServiceAT, state.ServiceAT},
+		{plan.KeyDates, state.KeyDates},
+		{plan.ServiceCloud, state.ServiceCloud},
+	}
+	for _, v := range values {
+		if !v.plan.Equal(v.state) {
+			return true
+		}
 	}
 
-	serviceAT := map[string]interface{}{}
+	return false
+}
 
-	var requiredServices []int
-	requiredServicesList := strings.Split(at.RequiredServices, ",")
-	if len(requiredServicesList) > 0 {
-		for _, requiredService := range requiredServicesList {
-			if requiredService == "" {
-				continue
-			}
+func (r *ciResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state ciResourceModel
 
-			id, err := strconv.Atoi(requiredService)
-			if err != nil {
-				return diag.FromErr(err)
-			}
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if state.DeletionProtection.ValueBool() {
+		resp.Diagnostics.AddError("Deletion protection", fmt.Sprintf("cannot destroy CI %s without setting deletion_protection=false and running `terraform apply`", state.ID.ValueString()))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout(state.Timeouts, "delete", ciDeleteTimeout))
+	defer cancel()
+
+	if err := deleteCI(ctx, r.client, state.ID.ValueString()); err != nil {
+		resp.Diagnostics.AddError("Unable to delete CI", err.Error())
+	}
+}
+
+// ImportState sets the settings of the resource, which Idefix does not know,
+// to their defaults.
+func (r *ciResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("rollback_on_failure"), false)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("deletion_protection"), false)...)
+}
 
-			requiredServices = append(requiredServices, id)
+// containsInt reports whether ids holds id.
+func containsInt(ids []int, id int) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
 		}
 	}
-	serviceAT["required_services"] = requiredServices
 
-	var monitoringTools []int
-	monitoringToolList := strings.Split(at.MonitoringTool, ",")
-	if len(monitoringToolList) > 0 {
-		for _, monitoringTool := range monitoringToolList {
-			if monitoringTool == "" {
-				continue
-			}
+	return false
+}
 
-			id, err := strconv.Atoi(monitoringTool)
-			if err != nil {
-				return diag.FromErr(err)
-			}
+// splitIDs parses the comma separated list of IDs returned by Idefix.
+func splitIDs(s string) ([]int, error) {
+	var ids []int
+
+	for _, v := range strings.Split(s, ",") {
+		if v == "" {
+			continue
+		}
+
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
 
-			monitoringTools = append(monitoringTools, id)
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// joinIDs formats a list of IDs the way Idefix expects it.
+func joinIDs(ids []int) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.Itoa(id)
+	}
+
+	return strings.Join(s, ",")
+}
+
+// expandIDs returns the sorted IDs held by a set of integers.
+func expandIDs(s types.Set) []int {
+	var ids []int
+
+	for _, v := range s.Elements() {
+		if id, ok := v.(types.Int64); ok && !id.IsNull() && !id.IsUnknown() {
+			ids = append(ids, int(id.ValueInt64()))
 		}
 	}
-	serviceAT["monitoring_tool"] = monitoringTools
+	sort.Ints(ids)
 
-	var serviceATSet []interface{}
-	serviceATSet = append(serviceATSet, serviceAT)
-	d.Set("service_at", serviceATSet)
+	return ids
+}
 
-	return diags
+// expandNames returns the sorted names held by a set of strings.
+func expandNames(s types.Set) []string {
+	var names []string
+
+	for _, v := range s.Elements() {
+		if name, ok := v.(types.String); ok && !name.IsNull() && !name.IsUnknown() {
+			names = append(names, name.ValueString())
+		}
+	}
+	sort.Strings(names)
+
+	return names
 }
 
-func resourceCIUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*goidefix.Idefix)
+// int64Set returns the IDs as a set of integers, dropping the duplicates.
+func int64Set(ids []int) types.Set {
+	seen := make(map[int]bool, len(ids))
+	elements := make([]attr.Value, 0, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
 
-	ids := d.Get("project_ids").([]interface{})
-	projectIDs := make([]int, len(ids))
-	for i := range ids {
-		projectIDs[i] = ids[i].(int)
+		seen[id] = true
+		elements = append(elements, types.Int64Value(int64(id)))
 	}
 
-	_, err := client.CI.Update(ctx, &ci.UpdateRequest{
-		ID:              d.Id(),
-		Name:            d.Get("name").(string),
-		TypeID:          d.Get("type_id").(int),
-		CompanyID:       d.Get("company_id").(int),
-		ProjectIDs:      projectIDs,
-		OutSourcingName: d.Get("outsourcing_name").(string),
-		ServiceLevelID:  d.Get("service_level_id").(int),
-		Team:            d.Get("team").(string),
-		IsOwnerLBN:      d.Get("is_owner_lbn").(bool),
-		Comment:         d.Get("comment").(string),
-	})
-	if err != nil {
-		return diag.FromErr(err)
+	return types.SetValueMust(types.Int64Type, elements)
+}
+
+// stringSet returns the names as a set of strings, dropping the duplicates.
+func stringSet(names []string) types.Set {
+	seen := make(map[string]bool, len(names))
+	elements := make([]attr.Value, 0, len(names))
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+
+		seen[name] = true
+		elements = append(elements, types.StringValue(name))
 	}
 
-	if v, ok := d.GetOk("service_cloud"); ok && v.(*schema.Set).Len() > 0 {
-		for _, serviceCloudSet := range v.(*schema.Set).List() {
-			var subscriptionId, productID int
-			var regionID string
+	return types.SetValueMust(types.StringType, elements)
+}
 
-			serviceCloud, ok := serviceCloudSet.(map[string]interface{})
+// setBlocks returns the blocks held by a set, none when it is null or not
+// known yet.
+func setBlocks[T any](ctx context.Context, s types.Set) ([]T, diag.Diagnostics) {
+	var blocks []T
 
-			if !ok {
-				continue
-			}
+	if s.IsNull() || s.IsUnknown() {
+		return blocks, nil
+	}
 
-			if v, ok := serviceCloud["subscription_id"].(int); ok && v > 0 {
-				subscriptionId = v
-			}
+	diags := s.ElementsAs(ctx, &blocks, false)
 
-			if v, ok := serviceCloud["product_id"].(int); ok && v > 0 {
-				productID = v
-			}
+	return blocks, diags
+}
 
-			if v, ok := serviceCloud["region_id"].(int); ok && v > 0 {
-				regionID = strconv.Itoa(v)
-			}
+// diagsError returns the first error of diags, or nil if there is none.
+func diagsError(diags diag.Diagnostics) error {
+	for _, d := range diags.Errors() {
+		return fmt.Errorf("%s: %s", d.Summary(), d.Detail())
+	}
 
-			_, err := client.CI.UpdateServiceCloud(ctx, &ci.UpdateServiceCloudRequest{
-				ID:             d.Id(),
-				SubscriptionID: subscriptionId,
-				ProductID:      productID,
-				RegionID:       regionID,
-			})
-			if err != nil {
-				return diag.FromErr(err)
-			}
+	return nil
+}
+
+func flattenServiceCloud(sc *ci.ReadServiceCloudResponse) (ciServiceCloudModel, error) {
+	// Idefix returns an empty region for a CI without service cloud.
+	var regionID int
+	if sc.RegionID != "" {
+		var err error
+		regionID, err = strconv.Atoi(sc.RegionID)
+		if err != nil {
+			return ciServiceCloudModel{}, fmt.Errorf("invalid region ID %q: %w", sc.RegionID, err)
 		}
 	}
 
-	if v, ok := d.GetOk("key_dates"); ok && v.(*schema.Set).Len() > 0 {
-		for _, keyDatesSet := range v.(*schema.Set).List() {
-			var envIDs, funcIDs []int
+	return ciServiceCloudModel{
+		SubscriptionID: types.Int64Value(int64(sc.SubscriptionID)),
+		ProductID:      types.Int64Value(int64(sc.ProductID)),
+		RegionID:       types.Int64Value(int64(regionID)),
+	}, nil
+}
 
-			keyDates, ok := keyDatesSet.(map[string]interface{})
+// flattenKeyDates returns the key dates read from Idefix, which gives the
+// environments and functions by ID.
+func flattenKeyDates(kd *ci.ReadUseAndKeyDateResponse) (ciKeyDatesModel, error) {
+	envIDs, err := splitIDs(kd.EnvironmentIDs)
+	if err != nil {
+		return ciKeyDatesModel{}, err
+	}
 
-			if !ok {
-				continue
-			}
+	funcIDs, err := splitIDs(kd.FunctionIDs)
+	if err != nil {
+		return ciKeyDatesModel{}, err
+	}
 
-			if v, ok := keyDates["environment_ids"].([]interface{}); ok && len(v) > 0 {
-				for i := range v {
-					envIDs = append(envIDs, v[i].(int))
-				}
-			}
+	return ciKeyDatesModel{
+		EnvironmentIDs:   int64Set(envIDs),
+		EnvironmentNames: types.SetNull(types.StringType),
+		FunctionIDs:      int64Set(funcIDs),
+		FunctionNames:    types.SetNull(types.StringType),
+	}, nil
+}
 
-			if v, ok := keyDates["function_ids"].([]interface{}); ok && len(v) > 0 {
-				for i := range v {
-					funcIDs = append(funcIDs, v[i].(int))
-				}
-			}
+// expandKeyDatesIDs returns the IDs of the environments or functions of a
+// key_dates block, given by ids or resolved from their names through list.
+func expandKeyDatesIDs(ctx context.Context, client *apiClient, ids []int, names []string, kind string, list func(context.Context, *apiClient) ([]catalogItem, error)) ([]int, error) {
+	if len(ids) > 0 && len(names) > 0 {
+		return nil, fmt.Errorf("only one of %s_ids or %s_names can be set in key_dates", kind, kind)
+	}
 
-			_, err := client.CI.UpdateUseAndKeyDate(ctx, &ci.UpdateUseAndKeyDateRequest{
-				ID:             d.Id(),
-				EnvSelect:      0,
-				EnvironmentIDs: envIDs,
-				FuncSelect:     0,
-				FunctionIDs:    funcIDs,
-			})
-			if err != nil {
-				return diag.FromErr(err)
+	if len(names) > 0 {
+		items, err := list(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+
+		byName := make(map[string][]int, len(items))
+		for _, item := range items {
+			byName[item.Name] = append(byName[item.Name], item.ID)
+		}
+
+		for _, name := range names {
+			switch matches := byName[name]; len(matches) {
+			case 0:
+				return nil, fmt.Errorf("unknown %s %q in key_dates", kind, name)
+			case 1:
+				ids = append(ids, matches[0])
+			default:
+				return nil, fmt.Errorf("%s name %q in key_dates is ambiguous, it is used by the IDs %s in Idefix, set %s_ids instead", kind, name, joinIDs(matches), kind)
 			}
 		}
 	}
 
-	if v, ok := d.GetOk("service_at"); ok && v.(*schema.Set).Len() > 0 {
-		for _, serviceATSet := range v.(*schema.Set).List() {
-			var requiredServices, monitoringTool string
+	return ids, nil
+}
 
-			serviceAT, ok := serviceATSet.(map[string]interface{})
+// keyDatesByName converts the environments and functions of keyDate, read
+// from Idefix, back to names when they are given by name in prior, so that
+// they do not show a diff. When one of the IDs is not in the catalog anymore,
+// the IDs are kept.
+func keyDatesByName(ctx context.Context, client *apiClient, id string, prior ciKeyDatesModel, keyDate *ciKeyDatesModel) error {
+	kinds := []struct {
+		name  string
+		list  func(context.Context, *apiClient) ([]catalogItem, error)
+		prior types.Set
+		ids   *types.Set
+		names *types.Set
+	}{
+		{"environment", listEnvironments, prior.EnvironmentNames, &keyDate.EnvironmentIDs, &keyDate.EnvironmentNames},
+		{"function", listFunctions, prior.FunctionNames, &keyDate.FunctionIDs, &keyDate.FunctionNames},
+	}
+	for _, kind := range kinds {
+		if len(expandNames(kind.prior)) == 0 {
+			continue
+		}
 
-			if !ok {
-				continue
-			}
+		items, err := kind.list(ctx, client)
+		if err != nil {
+			return err
+		}
 
-			if v, ok := serviceAT["required_services"].([]interface{}); ok && len(v) > 0 {
-				var ids []string
-				for i := range v {
-					ids = append(ids, strconv.Itoa(v[i].(int)))
-				}
-				requiredServices = strings.Join(ids, ",")
-			}
+		byID := make(map[int]string, len(items))
+		for _, item := range items {
+			byID[item.ID] = item.Name
+		}
 
-			if v, ok := serviceAT["monitoring_tool"].([]interface{}); ok && len(v) > 0 {
-				var ids []string
-				for i := range v {
-					ids = append(ids, strconv.Itoa(v[i].(int)))
-				}
-				monitoringTool = strings.Join(ids, ",")
+		var names []string
+		for _, v := range expandIDs(*kind.ids) {
+			name, ok := byID[v]
+			if !ok {
+				// A retired entry has no name anymore, the block is kept
+				// with the IDs so that the plan shows it.
+				log.Printf("[WARN] Unknown %s ID %d in the key dates of CI %s, keeping the %s_ids", kind.name, v, id, kind.name)
+				names = nil
+				break
 			}
 
-			_, err := client.Equipment.UpdateAT(ctx, &equipment.UpdateATRequest{
-				ID:               d.Id(),
-				RequiredServices: requiredServices,
-				MonitoringTool:   monitoringTool,
-				BackupComment:    "Asset PaaS",
-			})
-			if err != nil {
-				return diag.FromErr(err)
-			}
+			names = append(names, name)
+		}
+		if names == nil {
+			continue
 		}
-	}
 
-	_, err = client.CI.UpdatePlatform(ctx, &ci.UpdatePlatformRequest{
-		ID: d.Id(),
-	})
-	if err != nil {
-		return diag.FromErr(err)
+		*kind.ids = types.SetNull(types.Int64Type)
+		*kind.names = stringSet(names)
 	}
 
-	return resourceCIRead(ctx, d, m)
+	return nil
 }
 
-func resourceCIDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	var diags diag.Diagnostics
+func flattenServiceAT(at *equipment.ReadATResponse) (ciServiceATModel, error) {
+	requiredServices, err := splitIDs(at.RequiredServices)
+	if err != nil {
+		return ciServiceATModel{}, err
+	}
+
+	monitoringTools, err := splitIDs(at.MonitoringTool)
+	if err != nil {
+		return ciServiceATModel{}, err
+	}
 
-	client := m.(*goidefix.Idefix)
+	return ciServiceATModel{
+		RequiredServices: int64Set(requiredServices),
+		MonitoringTool:   int64Set(monitoringTools),
+	}, nil
+}
 
-	id, err := strconv.Atoi(d.Id())
+// deleteCI removes the monitoring events of the CI, then the CI itself.
+func deleteCI(ctx context.Context, client *apiClient, ciID string) error {
+	id, err := strconv.Atoi(ciID)
 	if err != nil {
-		return diag.FromErr(err)
+		return err
 	}
 
 	events, err := client.Monitoring.SearchEvents(ctx, &monitoring.SearchEventsRequest{
 		EquipmentIDs: []int{id},
 	})
-	if err != nil {
-		return diag.FromErr(err)
+	if isNotFound(err) || (err == nil && events == nil) {
+		events = &[]monitoring.SearchEventsResponse{}
+	} else if err != nil {
+		return stepError(ctx, err, "searching the monitoring events of CI %s", ciID)
 	}
 
 	for _, event := range *events {
@@ -634,18 +1198,13 @@ func resourceCIDelete(ctx context.Context, d *schema.ResourceData, m interface{}
 			ID: event.ID,
 		})
 		if err != nil {
-			return diag.FromErr(err)
+			return stepError(ctx, err, "deleting the monitoring event %s of CI %s", event.ID, ciID)
 		}
 	}
 
 	_, err = client.Equipment.Delete(ctx, &equipment.DeleteRequest{
-		ID: d.Id(),
+		ID: ciID,
 	})
-	if err != nil {
-		return diag.FromErr(err)
-	}
 
-	d.SetId("")
-
-	return diags
+	return stepError(ctx, err, "deleting CI %s", ciID)
 }
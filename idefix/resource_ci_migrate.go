@@ -0,0 +1,100 @@
+package idefix
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+)
+
+// UpgradeState upgrades the states written by the SDK resource. Its version 0
+// stored project_ids and the ID lists of the key_dates and service_at blocks
+// as lists, its version 1 as sets.
+func (r *ciResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: {
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				upgradeRawState(ctx, req, resp, func(rawState map[string]interface{}) {
+					ciStateUpgradeV0(rawState)
+					ciStateUpgradeV1(rawState)
+				})
+			},
+		},
+		1: {
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				upgradeRawState(ctx, req, resp, ciStateUpgradeV1)
+			},
+		},
+	}
+}
+
+// ciStateUpgradeV0 turns the ID lists of a CI into sets. Lists and sets are
+// stored the same way in the state, only the duplicated values have to be
+// dropped.
+func ciStateUpgradeV0(rawState map[string]interface{}) {
+	rawState["project_ids"] = uniqueValues(rawState["project_ids"])
+
+	blocks := map[string][]string{
+		"service_at": {"required_services", "monitoring_tool"},
+		"key_dates":  {"environment_ids", "function_ids"},
+	}
+	for attr, lists := range blocks {
+		values, _ := rawState[attr].([]interface{})
+		for _, v := range values {
+			block, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			for _, l := range lists {
+				if _, ok := block[l]; ok {
+					block[l] = uniqueValues(block[l])
+				}
+			}
+		}
+	}
+}
+
+// ciStateUpgradeV1 stores the unset values of a CI the way the framework
+// does: the SDK stored an empty comment and empty ID or name lists in the
+// key_dates blocks, which are null in the configuration.
+func ciStateUpgradeV1(rawState map[string]interface{}) {
+	if rawState["comment"] == "" {
+		rawState["comment"] = nil
+	}
+
+	values, _ := rawState["key_dates"].([]interface{})
+	for _, v := range values {
+		block, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		for _, l := range []string{"environment_ids", "environment_names", "function_ids", "function_names"} {
+			if list, _ := block[l].([]interface{}); len(list) == 0 {
+				block[l] = nil
+			}
+		}
+	}
+}
+
+// uniqueValues drops the duplicated values of a list read from the state,
+// keeping the first occurrence of each.
+func uniqueValues(v interface{}) interface{} {
+	list, ok := v.([]interface{})
+	if !ok {
+		return v
+	}
+
+	seen := make(map[interface{}]bool, len(list))
+	values := make([]interface{}, 0, len(list))
+	for _, value := range list {
+		if seen[value] {
+			continue
+		}
+
+		seen[value] = true
+		values = append(values, value)
+	}
+
+	return values
+}
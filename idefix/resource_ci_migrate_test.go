@@ -0,0 +1,95 @@
+package idefix
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// testCIStateV0 is the state of a CI as stored by the provider before the
+// schema version 1, with duplicated IDs in its lists.
+const testCIStateV0 = `{
+  "id": "1234",
+  "name": "my-ci",
+  "type_id": 41,
+  "company_id": 1,
+  "project_ids": [10, 11, 10],
+  "outsourcing_name": "0 - Non-défini",
+  "service_level_id": 100000080,
+  "team": "Unix",
+  "is_owner_lbn": true,
+  "comment": "",
+  "service_at": [
+    {
+      "required_services": [1, 2, 1],
+      "monitoring_tool": [1]
+    }
+  ],
+  "key_dates": [
+    {
+      "environment_ids": [1, 1],
+      "function_ids": [2]
+    }
+  ],
+  "service_cloud": [
+    {
+      "subscription_id": 1,
+      "product_id": 1,
+      "region_id": 2
+    }
+  ]
+}`
+
+func TestResourceCIStateUpgradeV0(t *testing.T) {
+	var rawState map[string]interface{}
+	if err := json.Unmarshal([]byte(testCIStateV0), &rawState); err != nil {
+		t.Fatal(err)
+	}
+
+	ciStateUpgradeV0(rawState)
+	ciStateUpgradeV1(rawState)
+
+	keyDates := rawState["key_dates"].([]interface{})[0].(map[string]interface{})
+	checks := map[string]struct {
+		got  interface{}
+		want interface{}
+	}{
+		"project_ids":                  {rawState["project_ids"], []interface{}{10.0, 11.0}},
+		"service_at.required_services": {rawState["service_at"].([]interface{})[0].(map[string]interface{})["required_services"], []interface{}{1.0, 2.0}},
+		"key_dates.environment_ids":    {keyDates["environment_ids"], []interface{}{1.0}},
+		"key_dates.environment_names":  {keyDates["environment_names"], nil},
+		"comment":                      {rawState["comment"], nil},
+	}
+	for name, c := range checks {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s = %v, want %v", name, c.got, c.want)
+		}
+	}
+}
+
+func TestResourceCIStateUpgradeV0_provider(t *testing.T) {
+	s := testProviderServer(t, &apiClient{})
+
+	state, diags := s.upgrade("idefix_ci", 0, testCIStateV0)
+	s.fatalDiagnostics("upgrading the state", diags)
+
+	attrs := testAttrs(state)
+	checks := map[string]interface{}{
+		"id":          "1234",
+		"project_ids": []interface{}{10, 11},
+		"comment":     nil,
+		"key_dates": []interface{}{
+			map[string]interface{}{
+				"environment_ids":   []interface{}{1},
+				"environment_names": nil,
+				"function_ids":      []interface{}{2},
+				"function_names":    nil,
+			},
+		},
+	}
+	for k, want := range checks {
+		if got := attrs[k]; !reflect.DeepEqual(got, want) {
+			t.Errorf("upgraded %s = %v, want %v", k, got, want)
+		}
+	}
+}
@@ -0,0 +1,725 @@
+package idefix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/marty-macfly/goidefix/services/ci"
+	"github.com/marty-macfly/goidefix/services/equipment"
+	"github.com/marty-macfly/goidefix/services/monitoring"
+	"github.com/marty-macfly/goidefix/services/project"
+	"github.com/marty-macfly/terraform-provider-idefix/internal/fakeidefix"
+)
+
+func init() {
+	resource.AddTestSweepers("idefix_ci", &resource.Sweeper{
+		Name: "idefix_ci",
+		F:    sweepCIs,
+	})
+}
+
+// sweepCIs deletes the CIs left by the tests in their company, along with
+// their monitoring events.
+func sweepCIs(_ string) error {
+	ctx := context.Background()
+
+	client, companyID, err := sweeperClient()
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.CI.Search(ctx, &ci.SearchRequest{
+		Name:      testAccPrefix,
+		CompanyID: companyID,
+	})
+	if isNotFound(err) || (err == nil && resp == nil) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("searching CIs: %w", err)
+	}
+
+	for _, c := range *resp {
+		if c.CompanyID != companyID || !strings.HasPrefix(c.Name, testAccPrefix) {
+			continue
+		}
+
+		log.Printf("[INFO] Deleting CI %s (%s)", c.ID, c.Name)
+		if err := deleteCI(ctx, client, c.ID); err != nil && !isNotFound(err) {
+			return fmt.Errorf("deleting CI %s: %w", c.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func TestAccResourceCI_basic(t *testing.T) {
+	api := testAccIdefix(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV5ProviderFactories: testAccProtoV5ProviderFactories,
+		CheckDestroy:             testAccCheckCIDestroy(api),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(api, testAccResourceCIConfig(api, "tf-acc-ci", "Unix")),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckCIExists(api, "idefix_ci.test"),
+					testAccAddMonitoringEvent(api, "idefix_ci.test"),
+					resource.TestCheckResourceAttr("idefix_ci.test", "name", "tf-acc-ci"),
+					resource.TestCheckResourceAttr("idefix_ci.test", "team", "Unix"),
+					resource.TestCheckResourceAttr("idefix_ci.test", "project_ids.#", "2"),
+					resource.TestCheckResourceAttr("idefix_ci.test", "service_cloud.#", "1"),
+					resource.TestCheckResourceAttr("idefix_ci.test", "key_dates.#", "1"),
+					resource.TestCheckResourceAttr("idefix_ci.test", "service_at.#", "1"),
+				),
+			},
+			{
+				Config: testAccConfig(api, testAccResourceCIConfig(api, "tf-acc-ci-renamed", "Windows")),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckCIExists(api, "idefix_ci.test"),
+					resource.TestCheckResourceAttr("idefix_ci.test", "name", "tf-acc-ci-renamed"),
+					resource.TestCheckResourceAttr("idefix_ci.test", "team", "Windows"),
+				),
+			},
+			{
+				ResourceName:            "idefix_ci.test",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"deletion_protection", "rollback_on_failure"},
+			},
+		},
+	})
+}
+
+func TestAccResourceCI_keyDatesByName(t *testing.T) {
+	api := testAccIdefix(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV5ProviderFactories: testAccProtoV5ProviderFactories,
+		CheckDestroy:             testAccCheckCIDestroy(api),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(api, fmt.Sprintf(`
+resource "idefix_project" "test" {
+  name                = "tf-acc-project"
+  company_id          = %[1]d
+  contract_number     = "C-0001"
+  deletion_protection = false
+}
+
+data "idefix_environments" "all" {
+}
+
+data "idefix_functions" "all" {
+}
+
+resource "idefix_ci" "test" {
+  name        = "tf-acc-ci"
+  company_id  = %[1]d
+  project_ids = [idefix_project.test.id]
+
+  key_dates {
+    environment_names = [data.idefix_environments.all.environments[0].name]
+    function_names    = [data.idefix_functions.all.functions[0].name]
+  }
+}
+`, api.CompanyID)),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckCIExists(api, "idefix_ci.test"),
+					resource.TestCheckResourceAttr("idefix_ci.test", "key_dates.#", "1"),
+					resource.TestCheckTypeSetElemAttrPair("idefix_ci.test", "key_dates.*.environment_names.*", "data.idefix_environments.all", "environments.0.name"),
+					resource.TestCheckTypeSetElemAttrPair("idefix_ci.test", "key_dates.*.function_names.*", "data.idefix_functions.all", "functions.0.name"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceCI_unknownCatalog(t *testing.T) {
+	api := testAccIdefix(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV5ProviderFactories: testAccProtoV5ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(api, fmt.Sprintf(`
+resource "idefix_ci" "test" {
+  name        = "tf-acc-ci"
+  company_id  = %d
+  project_ids = [1]
+  type_id     = 999
+}
+`, api.CompanyID)),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("unknown CI type ID 999"),
+			},
+		},
+	})
+}
+
+func TestResourceCI_crud(t *testing.T) {
+	api := testIdefix(t)
+	s := testProviderServer(t, api.Client)
+	ctx := context.Background()
+
+	projectID, _ := strconv.Atoi(api.createProject(t, project.CreateRequest{
+		Name: "tf-acc-project",
+	}))
+	config := func(name string, team string) map[string]interface{} {
+		return map[string]interface{}{
+			"name":        name,
+			"company_id":  api.CompanyID,
+			"project_ids": []interface{}{projectID},
+			"team":        team,
+			"service_cloud": []interface{}{
+				map[string]interface{}{
+					"subscription_id": 1,
+					"product_id":      1,
+					"region_id":       2,
+				},
+			},
+			"key_dates": []interface{}{
+				map[string]interface{}{
+					"environment_names": []interface{}{"Production"},
+					"function_ids":      []interface{}{1, 2},
+				},
+			},
+			"service_at": []interface{}{
+				map[string]interface{}{
+					"required_services": []interface{}{1, 2},
+					"monitoring_tool":   []interface{}{1},
+				},
+			},
+		}
+	}
+
+	state, diags := s.create("idefix_ci", config("tf-acc-ci", "Unix"))
+	s.fatalDiagnostics("creating", diags)
+	id, _ := testAttrs(state)["id"].(string)
+	if id == "" {
+		t.Fatal("creating: no ID set")
+	}
+	state = s.checkNoChanges("idefix_ci", state, config("tf-acc-ci", "Unix"))
+
+	state, diags = s.apply("idefix_ci", state, config("tf-acc-ci-renamed", "Windows"))
+	s.fatalDiagnostics("updating", diags)
+
+	state = s.checkNoChanges("idefix_ci", state, config("tf-acc-ci-renamed", "Windows"))
+	attrs := testAttrs(state)
+	checks := map[string]interface{}{
+		"name":          "tf-acc-ci-renamed",
+		"team":          "Windows",
+		"project_ids":   []interface{}{projectID},
+		"service_cloud": config("", "")["service_cloud"],
+		"service_at":    config("", "")["service_at"],
+		"key_dates": []interface{}{
+			map[string]interface{}{
+				"environment_ids":   nil,
+				"environment_names": []interface{}{"Production"},
+				"function_ids":      []interface{}{1, 2},
+				"function_names":    nil,
+			},
+		},
+	}
+	for k, want := range checks {
+		if got := attrs[k]; !reflect.DeepEqual(got, want) {
+			t.Errorf("read %s = %v, want %v", k, got, want)
+		}
+	}
+
+	sc, err := api.Client.CI.ReadServiceCloud(ctx, &ci.ReadServiceCloudRequest{
+		ID: id,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.RegionID != "2" {
+		t.Errorf("service cloud region %q, want 2", sc.RegionID)
+	}
+	kd, err := api.Client.CI.ReadUseAndKeyDate(ctx, &ci.ReadUseAndKeyDateRequest{
+		ID: id,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kd.EnvironmentIDs != "1" || kd.FunctionIDs != "1,2" {
+		t.Errorf("key dates environments %q and functions %q, want 1 and 1,2", kd.EnvironmentIDs, kd.FunctionIDs)
+	}
+
+	imported, diags := s.importState("idefix_ci", id)
+	s.fatalDiagnostics("importing", diags)
+	for k, want := range map[string]interface{}{"name": "tf-acc-ci-renamed", "rollback_on_failure": false, "deletion_protection": false} {
+		if got := testAttrs(imported)[k]; got != want {
+			t.Errorf("imported %s = %v, want %v", k, got, want)
+		}
+	}
+	if n := len(testAttrs(imported)["key_dates"].([]interface{})); n != 1 {
+		t.Errorf("imported %d key_dates blocks, want 1", n)
+	}
+
+	if api.Fake != nil {
+		api.Fake.AddMonitoringEvent(id)
+	}
+	state, diags = s.destroy("idefix_ci", state)
+	s.fatalDiagnostics("deleting", diags)
+	if !state.IsNull() {
+		t.Errorf("state %s left after deleting", testCanonical(state))
+	}
+	if _, err := api.Client.CI.Read(ctx, &ci.ReadRequest{ID: id}); !isNotFound(err) {
+		t.Errorf("reading deleted CI: got %v, want a not found error", err)
+	}
+	if api.Fake != nil && api.Fake.MonitoringEvents() != 0 {
+		t.Errorf("%d monitoring events left after deleting the CI", api.Fake.MonitoringEvents())
+	}
+}
+
+func TestResourceCIValidate_paths(t *testing.T) {
+	api := testFakeIdefix(t)
+	s := testProviderServer(t, api.Client)
+	projectID, _ := strconv.Atoi(api.createProject(t, project.CreateRequest{
+		Name: "tf-acc-project",
+	}))
+
+	cases := []struct {
+		config map[string]interface{}
+		path   string
+		want   string
+	}{
+		{
+			map[string]interface{}{"type_id": 99},
+			"type_id",
+			"unknown CI type ID 99",
+		},
+		{
+			map[string]interface{}{"company_id": 99},
+			"company_id",
+			"company 99 not found",
+		},
+		{
+			map[string]interface{}{"project_ids": []interface{}{999999}},
+			"project_ids",
+			"project 999999 not found",
+		},
+		{
+			map[string]interface{}{
+				"service_at": []interface{}{
+					map[string]interface{}{
+						"required_services": []interface{}{1},
+						"monitoring_tool":   []interface{}{99},
+					},
+				},
+			},
+			"service_at",
+			"monitoring_tool: unknown monitoring tool ID 99",
+		},
+		{
+			map[string]interface{}{
+				"key_dates": []interface{}{
+					map[string]interface{}{},
+				},
+			},
+			"key_dates",
+			"Each block must set one of environment_ids or environment_names.",
+		},
+		{
+			map[string]interface{}{
+				"key_dates": []interface{}{
+					map[string]interface{}{
+						"environment_ids": []interface{}{1},
+						"function_ids":    []interface{}{1},
+						"function_names":  []interface{}{"Web"},
+					},
+				},
+			},
+			"key_dates",
+			"A block sets both function_ids and function_names, only one of them can be set.",
+		},
+	}
+
+	for _, c := range cases {
+		config := map[string]interface{}{
+			"name":        "tf-acc-ci",
+			"company_id":  api.CompanyID,
+			"project_ids": []interface{}{projectID},
+		}
+		for k, v := range c.config {
+			config[k] = v
+		}
+
+		_, _, _, diags := s.plan("idefix_ci", tftypes.NewValue(s.resourceSchema("idefix_ci").ValueType(), nil), config)
+		d := testDiagnostic(diags, tfprotov5.DiagnosticSeverityError)
+		if d == nil || d.Detail != c.want || !d.Attribute.Equal(testAttributePath(c.path)) {
+			t.Errorf("got %s, want %q on %s", testDiagnostics(diags), c.want, c.path)
+		}
+	}
+}
+
+func TestExpandKeyDatesIDs_ambiguous(t *testing.T) {
+	api := testFakeIdefix(t)
+
+	environments := fakeidefix.Environments
+	fakeidefix.Environments = append(append([]ci.ListEnvironmentsResponse{}, environments...), ci.ListEnvironmentsResponse{ID: 3, Name: "Production"})
+	t.Cleanup(func() {
+		fakeidefix.Environments = environments
+	})
+
+	_, err := expandKeyDatesIDs(context.Background(), api.Client, nil, []string{"Production"}, "environment", listEnvironments)
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("got %v, want an error about the ambiguous name", err)
+	}
+
+	ids, err := expandKeyDatesIDs(context.Background(), api.Client, nil, []string{"Staging"}, "environment", listEnvironments)
+	if err != nil || len(ids) != 1 || ids[0] != 2 {
+		t.Errorf("got %v, %v, want [2]", ids, err)
+	}
+}
+
+func TestFlattenServiceCloud(t *testing.T) {
+	for _, c := range []struct {
+		regionID string
+		want     int64
+		wantErr  bool
+	}{
+		{"2", 2, false},
+		{"", 0, false},
+		{"France Central", 0, true},
+	} {
+		got, err := flattenServiceCloud(&ci.ReadServiceCloudResponse{SubscriptionID: 1, ProductID: 1, RegionID: c.regionID})
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("region %q: got %v, want an error", c.regionID, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("region %q: %s", c.regionID, err)
+			continue
+		}
+		if regionID := got.RegionID.ValueInt64(); regionID != c.want {
+			t.Errorf("region %q: got region_id %d, want %d", c.regionID, regionID, c.want)
+		}
+	}
+}
+
+func TestKeyDatesByName_unknownID(t *testing.T) {
+	api := testFakeIdefix(t)
+
+	prior := ciKeyDatesModel{
+		EnvironmentIDs:   types.SetNull(types.Int64Type),
+		EnvironmentNames: stringSet([]string{fakeidefix.Environments[0].Name}),
+		FunctionIDs:      types.SetNull(types.Int64Type),
+		FunctionNames:    stringSet([]string{fakeidefix.Functions[0].Name}),
+	}
+
+	// The second environment has been retired from the catalog.
+	keyDate := ciKeyDatesModel{
+		EnvironmentIDs:   int64Set([]int{fakeidefix.Environments[0].ID, 999}),
+		EnvironmentNames: types.SetNull(types.StringType),
+		FunctionIDs:      int64Set([]int{fakeidefix.Functions[0].ID}),
+		FunctionNames:    types.SetNull(types.StringType),
+	}
+	if err := keyDatesByName(context.Background(), api.Client, "1234", prior, &keyDate); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := expandIDs(keyDate.EnvironmentIDs); !reflect.DeepEqual(ids, []int{fakeidefix.Environments[0].ID, 999}) {
+		t.Errorf("got environment_ids %v, want them kept", ids)
+	}
+	if !keyDate.EnvironmentNames.IsNull() {
+		t.Errorf("got environment_names %v, want none", keyDate.EnvironmentNames)
+	}
+	if names := expandNames(keyDate.FunctionNames); !reflect.DeepEqual(names, []string{fakeidefix.Functions[0].Name}) {
+		t.Errorf("got function_names %v, want [%s]", names, fakeidefix.Functions[0].Name)
+	}
+	if !keyDate.FunctionIDs.IsNull() {
+		t.Errorf("got function_ids %v, want none", keyDate.FunctionIDs)
+	}
+}
+
+// normalizingCIAPI reads the CIs with their name in upper case, the way Idefix
+// may normalize the attributes of a CI after its creation.
+type normalizingCIAPI struct {
+	ciAPI
+}
+
+func (c normalizingCIAPI) Read(ctx context.Context, req *ci.ReadRequest) (*ci.ReadResponse, error) {
+	resp, err := c.ciAPI.Read(ctx, req)
+	if resp != nil {
+		resp.Name = strings.ToUpper(resp.Name)
+	}
+
+	return resp, err
+}
+
+func TestWaitForCI_normalized(t *testing.T) {
+	api := testFakeIdefix(t)
+	client := *api.Client
+	client.CI = normalizingCIAPI{client.CI}
+
+	resp, err := client.CI.Create(context.Background(), &ci.CreateRequest{
+		Name:      "tf-acc-ci",
+		CompanyID: api.CompanyID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := waitForCI(context.Background(), &client, resp.ID, 10*time.Second); err != nil {
+		t.Errorf("waiting for a CI read back with a normalized name: %s", err)
+	}
+}
+
+type failingPlatformCIAPI struct {
+	ciAPI
+}
+
+func (failingPlatformCIAPI) UpdatePlatform(context.Context, *ci.UpdatePlatformRequest) (*ci.UpdatePlatformResponse, error) {
+	return nil, errors.New("platform unavailable")
+}
+
+type failingDeleteEquipmentAPI struct {
+	equipmentAPI
+}
+
+func (failingDeleteEquipmentAPI) Delete(context.Context, *equipment.DeleteRequest) (*equipment.DeleteResponse, error) {
+	return nil, errors.New("equipment locked")
+}
+
+type nilEventsMonitoringAPI struct {
+	monitoringAPI
+}
+
+func (nilEventsMonitoringAPI) SearchEvents(context.Context, *monitoring.SearchEventsRequest) (*[]monitoring.SearchEventsResponse, error) {
+	return nil, nil
+}
+
+func TestResourceCICreate_rollbackFailure(t *testing.T) {
+	api := testFakeIdefix(t)
+	projectID, _ := strconv.Atoi(api.createProject(t, project.CreateRequest{
+		Name: "tf-acc-project",
+	}))
+
+	client := *api.Client
+	client.CI = failingPlatformCIAPI{client.CI}
+	client.Equipment = failingDeleteEquipmentAPI{client.Equipment}
+	s := testProviderServer(t, &client)
+
+	state, diags := s.create("idefix_ci", map[string]interface{}{
+		"name":                "tf-acc-ci",
+		"company_id":          api.CompanyID,
+		"project_ids":         []interface{}{projectID},
+		"rollback_on_failure": true,
+	})
+	if len(diags) != 2 {
+		t.Fatalf("got %d diagnostics, want the creation and rollback errors: %s", len(diags), testDiagnostics(diags))
+	}
+	if !strings.Contains(diags[0].Detail, "platform unavailable") {
+		t.Errorf("first error %q, want the creation error", diags[0].Detail)
+	}
+	if !strings.HasPrefix(diags[1].Summary, "Unable to roll back") || !strings.Contains(diags[1].Detail, "equipment locked") {
+		t.Errorf("second error %q: %q, want the rollback error", diags[1].Summary, diags[1].Detail)
+	}
+	if id, _ := testAttrs(state)["id"].(string); id == "" {
+		t.Error("the CI which could not be rolled back was removed from state")
+	}
+}
+
+func TestDeleteCI_noEvents(t *testing.T) {
+	api := testFakeIdefix(t)
+	client := *api.Client
+	client.Monitoring = nilEventsMonitoringAPI{client.Monitoring}
+
+	resp, err := client.CI.Create(context.Background(), &ci.CreateRequest{
+		Name:      "tf-acc-ci",
+		CompanyID: api.CompanyID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := deleteCI(context.Background(), &client, resp.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CI.Read(context.Background(), &ci.ReadRequest{ID: resp.ID}); !isNotFound(err) {
+		t.Errorf("reading deleted CI: got %v, want a not found error", err)
+	}
+}
+
+// TestResourceCIRead_missingDetails checks that the 404 answered by Idefix
+// for the details of a CI, decoded by goidefix, keeps the CI in state.
+func TestResourceCIRead_missingDetails(t *testing.T) {
+	api := testFakeIdefix(t)
+
+	resp, err := api.Client.CI.Create(context.Background(), &ci.CreateRequest{
+		Name:      "tf-acc-ci",
+		TypeID:    fakeidefix.CITypes[0].ID,
+		CompanyID: api.CompanyID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.Fake.RemoveCIDetails(resp.ID)
+
+	if _, err := api.Client.CI.ReadServiceCloud(context.Background(), &ci.ReadServiceCloudRequest{ID: resp.ID}); !isNotFound(err) {
+		t.Fatalf("reading the missing service cloud: got %v, want a not found error", err)
+	}
+
+	s := testProviderServer(t, api.Client)
+
+	state, diags := s.importState("idefix_ci", resp.ID)
+	s.fatalDiagnostics("importing", diags)
+	if id := testAttrs(state)["id"]; id != resp.ID {
+		t.Fatalf("got ID %v, want the CI %s kept in state", id, resp.ID)
+	}
+	for _, k := range []string{"service_cloud", "key_dates", "service_at"} {
+		if blocks := testAttrs(state)[k].([]interface{}); len(blocks) != 0 {
+			t.Errorf("got %d %s blocks, want none", len(blocks), k)
+		}
+	}
+
+	if _, diags := s.readDataSource("idefix_ci", map[string]interface{}{"id": resp.ID}); testHasError(diags) {
+		t.Errorf("data source: %s", testDiagnostics(diags))
+	}
+}
+
+// testAccResourceCIConfig creates a CI using the first entries of the
+// catalogs, so that it can be recorded against any Idefix.
+func testAccResourceCIConfig(api *testAccAPI, name string, team string) string {
+	return fmt.Sprintf(`
+data "idefix_cloud_subscriptions" "test" {
+  company_id = %[1]d
+}
+
+data "idefix_cloud_products" "all" {
+}
+
+data "idefix_cloud_regions" "all" {
+}
+
+data "idefix_environments" "all" {
+}
+
+data "idefix_functions" "all" {
+}
+
+data "idefix_required_services" "all" {
+}
+
+data "idefix_monitoring_tools" "all" {
+}
+
+resource "idefix_project" "test" {
+  count = 2
+
+  name                = "tf-acc-project-${count.index}"
+  company_id          = %[1]d
+  contract_number     = "C-0001"
+  deletion_protection = false
+}
+
+resource "idefix_ci" "test" {
+  name        = %[2]q
+  company_id  = %[1]d
+  project_ids = idefix_project.test[*].id
+  team        = %[3]q
+
+  service_cloud {
+    subscription_id = data.idefix_cloud_subscriptions.test.cloud_subscriptions[0].id
+    product_id      = data.idefix_cloud_products.all.cloud_products[0].id
+    region_id       = data.idefix_cloud_regions.all.cloud_regions[0].id
+  }
+
+  key_dates {
+    environment_ids = [for e in slice(data.idefix_environments.all.environments, 0, 2) : e.id]
+    function_ids    = [data.idefix_functions.all.functions[0].id]
+  }
+
+  service_at {
+    required_services = [for s in slice(data.idefix_required_services.all.required_services, 0, 2) : s.id]
+    monitoring_tool   = [data.idefix_monitoring_tools.all.monitoring_tools[0].id]
+  }
+}
+`, api.CompanyID, name, team)
+}
+
+func testAccCheckCIExists(api *testAccAPI, name string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[name]
+		if !ok {
+			return fmt.Errorf("%s not found in state", name)
+		}
+
+		c, err := api.Client.CI.Read(context.Background(), &ci.ReadRequest{
+			ID: rs.Primary.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("reading CI %s: %w", rs.Primary.ID, err)
+		}
+		if c.Name != rs.Primary.Attributes["name"] {
+			return fmt.Errorf("CI %s is named %q in Idefix, want %q", rs.Primary.ID, c.Name, rs.Primary.Attributes["name"])
+		}
+
+		return nil
+	}
+}
+
+// testAccAddMonitoringEvent attaches a monitoring event to a CI, which must be
+// removed along with it. Idefix has no API to raise events, so it only works
+// against the fake.
+func testAccAddMonitoringEvent(api *testAccAPI, name string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		if api.Fake == nil {
+			return nil
+		}
+
+		rs, ok := s.RootModule().Resources[name]
+		if !ok {
+			return fmt.Errorf("%s not found in state", name)
+		}
+
+		api.Fake.AddMonitoringEvent(rs.Primary.ID)
+
+		return nil
+	}
+}
+
+func testAccCheckCIDestroy(api *testAccAPI) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		for _, rs := range s.RootModule().Resources {
+			if rs.Type != "idefix_ci" {
+				continue
+			}
+
+			_, err := api.Client.CI.Read(context.Background(), &ci.ReadRequest{
+				ID: rs.Primary.ID,
+			})
+			if err == nil {
+				return fmt.Errorf("CI %s still exists", rs.Primary.ID)
+			}
+			if !isNotFound(err) {
+				return fmt.Errorf("reading CI %s: %w", rs.Primary.ID, err)
+			}
+		}
+
+		if api.Fake != nil {
+			if n := api.Fake.MonitoringEvents(); n != 0 {
+				return fmt.Errorf("%d monitoring events still exist", n)
+			}
+		}
+
+		return testAccCheckProjectDestroy(api)(s)
+	}
+}
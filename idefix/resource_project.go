@@ -2,196 +2,496 @@ package idefix
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+	"time"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/marty-macfly/goidefix"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/marty-macfly/goidefix/services/project"
 )
 
-func resourceProject() *schema.Resource {
-	return &schema.Resource{
-		CreateContext: resourceProjectCreate,
-		ReadContext:   resourceProjectRead,
-		UpdateContext: resourceProjectUpdate,
-		DeleteContext: resourceProjectDelete,
-		Description:   "Manages project.",
-		Schema: map[string]*schema.Schema{
-			"id": {
-				Description: "The id of the project.",
-				Type:        schema.TypeString,
+var (
+	_ resource.ResourceWithConfigure    = &projectResource{}
+	_ resource.ResourceWithModifyPlan   = &projectResource{}
+	_ resource.ResourceWithImportState  = &projectResource{}
+	_ resource.ResourceWithUpgradeState = &projectResource{}
+)
+
+// The timeouts of the operations on a project, when the timeouts block does
+// not set them.
+const (
+	projectCreateTimeout = 5 * time.Minute
+	projectReadTimeout   = 5 * time.Minute
+	projectUpdateTimeout = 5 * time.Minute
+	projectDeleteTimeout = 5 * time.Minute
+)
+
+type projectResource struct {
+	client *apiClient
+}
+
+type projectResourceModel struct {
+	ID                 types.String `tfsdk:"id"`
+	Name               types.String `tfsdk:"name"`
+	CompanyID          types.Int64  `tfsdk:"company_id"`
+	ParentID           types.Int64  `tfsdk:"parent_id"`
+	WbsFrance          types.String `tfsdk:"wbs_france"`
+	WbsVietnam         types.String `tfsdk:"wbs_vietnam"`
+	WbsSingapour       types.String `tfsdk:"wbs_singapour"`
+	WbsMaurice         types.String `tfsdk:"wbs_maurice"`
+	WbsLuxembourg      types.String `tfsdk:"wbs_luxembourg"`
+	WbsHongKong        types.String `tfsdk:"wbs_hong_kong"`
+	WbsChine           types.String `tfsdk:"wbs_chine"`
+	WbsCanada          types.String `tfsdk:"wbs_canada"`
+	WbsBelgique        types.String `tfsdk:"wbs_belgique"`
+	ContractNumber     types.String `tfsdk:"contract_number"`
+	AdoptExisting      types.Bool   `tfsdk:"adopt_existing"`
+	DeletionProtection types.Bool   `tfsdk:"deletion_protection"`
+	Timeouts           types.Object `tfsdk:"timeouts"`
+}
+
+func newProjectResource() resource.Resource {
+	return &projectResource{}
+}
+
+func (r *projectResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_project"
+}
+
+func (r *projectResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = projectResourceSchema()
+}
+
+// projectResourceSchema is the schema of the project resource. Its version 0
+// is the schema of the SDK resource, which has the same attributes.
+func projectResourceSchema() schema.Schema {
+	wbs := func() schema.StringAttribute {
+		return schema.StringAttribute{
+			Optional:    true,
+			Description: "The WBS of this project",
+		}
+	}
+
+	return schema.Schema{
+		Description: "Manages project.",
+		Version:     1,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
 				Computed:    true,
+				Description: "The id of the project.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
 			},
-			"name": {
-				Type:        schema.TypeString,
+			"name": schema.StringAttribute{
 				Required:    true,
 				Description: "The name of project (must be unique).",
 			},
-			"company_id": {
-				Type:        schema.TypeInt,
+			"company_id": schema.Int64Attribute{
 				Required:    true,
 				Description: "The company ID associated to the CI.",
 			},
-			"parent_id": {
-				Type:        schema.TypeInt,
+			"parent_id": schema.Int64Attribute{
 				Optional:    true,
 				Description: "The ID of the parent project.",
 			},
-			"wbs_france": {
-				Type:        schema.TypeString,
-				Optional:    true,
-				Description: "The WBS of this project",
-			},
-			"wbs_vietnam": {
-				Type:        schema.TypeString,
-				Optional:    true,
-				Description: "The WBS of this project",
-			},
-			"wbs_singapour": {
-				Type:        schema.TypeString,
-				Optional:    true,
-				Description: "The WBS of this project",
-			},
-			"wbs_maurice": {
-				Type:        schema.TypeString,
-				Optional:    true,
-				Description: "The WBS of this project",
-			},
-			"wbs_luxembourg": {
-				Type:        schema.TypeString,
-				Optional:    true,
-				Description: "The WBS of this project",
-			},
-			"wbs_hong_kong": {
-				Type:        schema.TypeString,
-				Optional:    true,
-				Description: "The WBS of this project",
-			},
-			"wbs_chine": {
-				Type:        schema.TypeString,
-				Optional:    true,
-				Description: "The WBS of this project",
+			"wbs_france":     wbs(),
+			"wbs_vietnam":    wbs(),
+			"wbs_singapour":  wbs(),
+			"wbs_maurice":    wbs(),
+			"wbs_luxembourg": wbs(),
+			"wbs_hong_kong":  wbs(),
+			"wbs_chine":      wbs(),
+			"wbs_canada":     wbs(),
+			"wbs_belgique":   wbs(),
+			"contract_number": schema.StringAttribute{
+				Required:    true,
+				Description: "Contract number",
 			},
-			"wbs_canada": {
-				Type:        schema.TypeString,
+			"adopt_existing": schema.BoolAttribute{
 				Optional:    true,
-				Description: "The WBS of this project",
+				Computed:    true,
+				Description: "Whether an existing project with the same name in the same company is adopted on creation instead of failing the plan. The plan warns when a project will be adopted: it is updated with the configuration, which overwrites its parent, `wbs_*` and `contract_number` in Idefix. Defaults to `false`.",
+				PlanModifiers: []planmodifier.Bool{
+					defaultBool(false),
+				},
 			},
-			"wbs_belgique": {
-				Type:        schema.TypeString,
+			"deletion_protection": schema.BoolAttribute{
 				Optional:    true,
-				Description: "The WBS of this project",
-			},
-			"contract_number": {
-				Type:        schema.TypeString,
-				Required:    true,
-				Description: "Contract number",
+				Computed:    true,
+				Description: "Whether Terraform will be prevented from destroying the project. When set to `true`, a `terraform destroy` or a replacement of the project will fail until it is set to `false` and applied. Defaults to `true`.",
+				PlanModifiers: []planmodifier.Bool{
+					defaultBool(true),
+				},
 			},
 		},
-		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
+		Blocks: map[string]schema.Block{
+			"timeouts": timeoutsBlock(),
 		},
 	}
 }
 
-func resourceProjectCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*goidefix.Idefix)
-
-	project, err := client.Project.Create(ctx, &project.CreateRequest{
-		Name:           d.Get("name").(string),
-		CompanyID:      d.Get("company_id").(int),
-		ParentID:       d.Get("parent_id").(int),
-		WbsFrance:      d.Get("wbs_france").(string),
-		WbsVietnam:     d.Get("wbs_vietnam").(string),
-		WbsSingapour:   d.Get("wbs_singapour").(string),
-		WbsMaurice:     d.Get("wbs_maurice").(string),
-		WbsLuxembourg:  d.Get("wbs_luxembourg").(string),
-		WbsHongKong:    d.Get("wbs_hong_kong").(string),
-		WbsChine:       d.Get("wbs_chine").(string),
-		WbsCanada:      d.Get("wbs_canada").(string),
-		WbsBelgique:    d.Get("wbs_belgique").(string),
-		ContractNumber: d.Get("contract_number").(string),
+func (r *projectResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*apiClient)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected resource configure type", fmt.Sprintf("Expected *apiClient, got: %T.", req.ProviderData))
+		return
+	}
+
+	r.client = client
+}
+
+// ModifyPlan checks that no other project of the company already uses the
+// name of the project, and warns when an existing project will be adopted.
+func (r *projectResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan projectResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if plan.Name.IsUnknown() || plan.CompanyID.IsUnknown() {
+		return
+	}
+
+	var state *projectResourceModel
+	if !req.State.Raw.IsNull() {
+		state = &projectResourceModel{}
+		resp.Diagnostics.Append(req.State.Get(ctx, state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		if state.Name.Equal(plan.Name) && state.CompanyID.Equal(plan.CompanyID) {
+			return
+		}
+	}
+
+	name := plan.Name.ValueString()
+	companyID := int(plan.CompanyID.ValueInt64())
+
+	existing, err := findProjectByName(ctx, r.client, name, companyID)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("name"), "Unable to search project", err.Error())
+		return
+	}
+	if existing == nil || (state != nil && strconv.Itoa(existing.ID) == state.ID.ValueString()) {
+		return
+	}
+
+	if state == nil && plan.AdoptExisting.ValueBool() {
+		resp.Diagnostics.AddAttributeWarning(path.Root("adopt_existing"), "Existing project adopted",
+			fmt.Sprintf("Project %q already exists in company %d with ID %d. It will be adopted and updated with the configuration, which overwrites its parent, wbs_* and contract_number in Idefix.", name, companyID, existing.ID))
+		return
+	}
+
+	resp.Diagnostics.AddAttributeError(path.Root("name"), "Project already exists",
+		fmt.Sprintf("Project %q already exists in company %d with ID %d.", name, companyID, existing.ID))
+}
+
+// findProjectByName returns the project of the company named exactly name,
+// or nil if there is none.
+func findProjectByName(ctx context.Context, client *apiClient, name string, companyID int) (*project.SearchResponse, error) {
+	resp, err := client.Project.Search(ctx, &project.SearchRequest{
+		Name:      name,
+		CompanyID: companyID,
+	})
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil || resp == nil {
+		return nil, err
+	}
+
+	for _, p := range *resp {
+		if p.Name == name && p.CompanyID == companyID {
+			return &p, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (r *projectResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan projectResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	createTimeout := timeout(plan.Timeouts, "create", projectCreateTimeout)
+	ctx, cancel := context.WithTimeout(ctx, createTimeout)
+	defer cancel()
+
+	if plan.AdoptExisting.ValueBool() {
+		existing, err := findProjectByName(ctx, r.client, plan.Name.ValueString(), int(plan.CompanyID.ValueInt64()))
+		if err != nil {
+			resp.Diagnostics.AddError("Unable to search project", err.Error())
+			return
+		}
+
+		if existing != nil {
+			// The attributes of the project are part of the plan, which
+			// the state must match after the apply: the adopted project is
+			// updated with them, as the plan warned.
+			log.Printf("[INFO] Adopting existing project %d named %q", existing.ID, existing.Name)
+			plan.ID = types.StringValue(strconv.Itoa(existing.ID))
+
+			if _, err := r.client.Project.Update(ctx, projectUpdateRequest(plan)); err != nil {
+				resp.Diagnostics.AddError("Unable to adopt project", stepError(ctx, err, "updating project %s", plan.ID.ValueString()).Error())
+				return
+			}
+
+			resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+			return
+		}
+	}
+
+	p, err := r.client.Project.Create(ctx, &project.CreateRequest{
+		Name:           plan.Name.ValueString(),
+		CompanyID:      int(plan.CompanyID.ValueInt64()),
+		ParentID:       int(plan.ParentID.ValueInt64()),
+		WbsFrance:      plan.WbsFrance.ValueString(),
+		WbsVietnam:     plan.WbsVietnam.ValueString(),
+		WbsSingapour:   plan.WbsSingapour.ValueString(),
+		WbsMaurice:     plan.WbsMaurice.ValueString(),
+		WbsLuxembourg:  plan.WbsLuxembourg.ValueString(),
+		WbsHongKong:    plan.WbsHongKong.ValueString(),
+		WbsChine:       plan.WbsChine.ValueString(),
+		WbsCanada:      plan.WbsCanada.ValueString(),
+		WbsBelgique:    plan.WbsBelgique.ValueString(),
+		ContractNumber: plan.ContractNumber.ValueString(),
 		TypeName:       "Suivi",
 		InvoiceType:    "FDT",
 		InitialBudget:  "0",
 	})
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("Unable to create project", stepError(ctx, err, "creating project %s", plan.Name.ValueString()).Error())
+		return
 	}
 
-	d.SetId(project.ID)
+	plan.ID = types.StringValue(p.ID)
+
+	if err := waitForProject(ctx, r.client, p.ID, createTimeout); err != nil {
+		// The project is kept in the state, and tainted, as it exists.
+		resp.Diagnostics.AddError("Unable to create project", err.Error())
+	}
 
-	return resourceProjectRead(ctx, d, m)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
-func resourceProjectRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	var diags diag.Diagnostics
+// waitForProject polls Idefix until the project it has just created can be
+// read back.
+func waitForProject(ctx context.Context, client *apiClient, id string, timeout time.Duration) error {
+	err := waitUntilReadable(ctx, id, timeout, func() (bool, error) {
+		p, err := client.Project.Read(ctx, &project.ReadRequest{
+			ID: id,
+		})
+
+		return p != nil, err
+	})
+
+	return stepError(ctx, err, "waiting for project %s to be available", id)
+}
 
-	client := m.(*goidefix.Idefix)
+func (r *projectResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state projectResourceModel
 
-	project, err := client.Project.Read(ctx, &project.ReadRequest{
-		ID: d.Id(),
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout(state.Timeouts, "read", projectReadTimeout))
+	defer cancel()
+
+	p, err := r.client.Project.Read(ctx, &project.ReadRequest{
+		ID: state.ID.ValueString(),
 	})
+	if isNotFound(err) || (err == nil && p == nil) {
+		log.Printf("[WARN] Project %s not found, removing from state", state.ID.ValueString())
+		resp.State.RemoveResource(ctx)
+
+		return
+	}
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("Unable to read project", stepError(ctx, err, "reading project %s", state.ID.ValueString()).Error())
+		return
 	}
 
-	if project == nil {
-		d.SetId("")
+	// Idefix does not return the wbs_* and contract_number of a project,
+	// they are kept as they were applied.
+	state.Name = types.StringValue(p.Name)
+	state.CompanyID = types.Int64Value(int64(p.CompanyID))
+	state.ParentID = int64OrNull(p.ParentID, state.ParentID)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *projectResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state projectResourceModel
 
-		return diags
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	d.SetId(d.Id())
-	d.Set("name", project.Name)
-	d.Set("company_id", project.CompanyID)
-	d.Set("parent_id", project.ParentID)
+	ctx, cancel := context.WithTimeout(ctx, timeout(plan.Timeouts, "update", projectUpdateTimeout))
+	defer cancel()
 
-	return diags
-}
+	// Idefix is only called when the project itself changes, not only the
+	// settings of the resource.
+	if !reflect.DeepEqual(projectUpdateRequest(plan), projectUpdateRequest(state)) {
+		if _, err := r.client.Project.Update(ctx, projectUpdateRequest(plan)); err != nil {
+			resp.Diagnostics.AddError("Unable to update project", stepError(ctx, err, "updating project %s", plan.ID.ValueString()).Error())
+			return
+		}
+	}
 
-func resourceProjectUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*goidefix.Idefix)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
 
-	_, err := client.Project.Update(ctx, &project.UpdateRequest{
-		ID:             d.Id(),
-		Name:           d.Get("name").(string),
-		CompanyID:      d.Get("company_id").(int),
-		ParentID:       d.Get("parent_id").(int),
-		WbsFrance:      d.Get("wbs_france").(string),
-		WbsVietnam:     d.Get("wbs_vietnam").(string),
-		WbsSingapour:   d.Get("wbs_singapour").(string),
-		WbsMaurice:     d.Get("wbs_maurice").(string),
-		WbsLuxembourg:  d.Get("wbs_luxembourg").(string),
-		WbsHongKong:    d.Get("wbs_hong_kong").(string),
-		WbsChine:       d.Get("wbs_chine").(string),
-		WbsCanada:      d.Get("wbs_canada").(string),
-		WbsBelgique:    d.Get("wbs_belgique").(string),
-		ContractNumber: d.Get("contract_number").(string),
+// projectUpdateRequest returns the request updating the project with the
+// attributes of m.
+func projectUpdateRequest(m projectResourceModel) *project.UpdateRequest {
+	return &project.UpdateRequest{
+		ID:             m.ID.ValueString(),
+		Name:           m.Name.ValueString(),
+		CompanyID:      int(m.CompanyID.ValueInt64()),
+		ParentID:       int(m.ParentID.ValueInt64()),
+		WbsFrance:      m.WbsFrance.ValueString(),
+		WbsVietnam:     m.WbsVietnam.ValueString(),
+		WbsSingapour:   m.WbsSingapour.ValueString(),
+		WbsMaurice:     m.WbsMaurice.ValueString(),
+		WbsLuxembourg:  m.WbsLuxembourg.ValueString(),
+		WbsHongKong:    m.WbsHongKong.ValueString(),
+		WbsChine:       m.WbsChine.ValueString(),
+		WbsCanada:      m.WbsCanada.ValueString(),
+		WbsBelgique:    m.WbsBelgique.ValueString(),
+		ContractNumber: m.ContractNumber.ValueString(),
 		TypeName:       "Suivi",
 		InvoiceType:    "FDT",
 		InitialBudget:  "0",
+	}
+}
+
+func (r *projectResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state projectResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if state.DeletionProtection.ValueBool() {
+		resp.Diagnostics.AddError("Deletion protection", fmt.Sprintf("cannot destroy project %s without setting deletion_protection=false and running `terraform apply`", state.ID.ValueString()))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout(state.Timeouts, "delete", projectDeleteTimeout))
+	defer cancel()
+
+	_, err := r.client.Project.Delete(ctx, &project.DeleteRequest{
+		ID: state.ID.ValueString(),
 	})
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("Unable to delete project", stepError(ctx, err, "deleting project %s", state.ID.ValueString()).Error())
 	}
+}
 
-	return resourceProjectRead(ctx, d, m)
+// ImportState sets the settings of the resource, which Idefix does not know,
+// to their defaults.
+func (r *projectResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("adopt_existing"), false)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("deletion_protection"), true)...)
 }
 
-func resourceProjectDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	var diags diag.Diagnostics
+// UpgradeState upgrades the states written by the SDK resource, whose parent_id
+// is 0 rather than null when the project has no parent.
+func (r *projectResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: {
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				upgradeRawState(ctx, req, resp, func(rawState map[string]interface{}) {
+					if rawState["parent_id"] == 0.0 {
+						rawState["parent_id"] = nil
+					}
+				})
+			},
+		},
+	}
+}
+
+// upgradeRawState decodes the JSON state of req, lets upgrade change it, and
+// sets it as the upgraded state of resp. The attributes missing from the
+// state are set to null.
+func upgradeRawState(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse, upgrade func(map[string]interface{})) {
+	if req.RawState == nil || req.RawState.JSON == nil {
+		resp.Diagnostics.AddError("Unable to upgrade state", "The state has no JSON value, it must be written by Terraform 0.12 or later.")
+		return
+	}
+
+	var rawState map[string]interface{}
+	if err := json.Unmarshal(req.RawState.JSON, &rawState); err != nil {
+		resp.Diagnostics.AddError("Unable to upgrade state", err.Error())
+		return
+	}
+
+	upgrade(rawState)
 
-	client := m.(*goidefix.Idefix)
+	b, err := json.Marshal(rawState)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to upgrade state", err.Error())
+		return
+	}
 
-	_, err := client.Project.Delete(ctx, &project.DeleteRequest{
-		ID: d.Id(),
+	v, err := tftypes.ValueFromJSONWithOpts(b, resp.State.Schema.Type().TerraformType(ctx), tftypes.ValueFromJSONOpts{
+		IgnoreUndefinedAttributes: true,
 	})
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("Unable to upgrade state", err.Error())
+		return
 	}
 
-	d.SetId("")
+	resp.State.Raw = v
+}
+
+// int64OrNull returns v read from Idefix, or null when v is 0, which is what
+// Idefix returns for an unset value, and prior is null.
+func int64OrNull(v int, prior types.Int64) types.Int64 {
+	if v == 0 && prior.IsNull() {
+		return types.Int64Null()
+	}
+
+	return types.Int64Value(int64(v))
+}
+
+// stringOrNull returns v read from Idefix, or null when v is empty, which is
+// what Idefix returns for an unset value, and prior is null.
+func stringOrNull(v string, prior types.String) types.String {
+	if v == "" && prior.IsNull() {
+		return types.StringNull()
+	}
 
-	return diags
+	return types.StringValue(v)
 }
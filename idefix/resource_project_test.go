@@ -0,0 +1,375 @@
+package idefix
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/marty-macfly/goidefix/services/project"
+)
+
+func init() {
+	resource.AddTestSweepers("idefix_project", &resource.Sweeper{
+		Name:         "idefix_project",
+		Dependencies: []string{"idefix_ci"},
+		F:            sweepProjects,
+	})
+}
+
+// sweepProjects deletes the projects left by the tests in their company, the
+// children before their parent.
+func sweepProjects(_ string) error {
+	ctx := context.Background()
+
+	client, companyID, err := sweeperClient()
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Project.Search(ctx, &project.SearchRequest{
+		Name:      testAccPrefix,
+		CompanyID: companyID,
+	})
+	if isNotFound(err) || (err == nil && resp == nil) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("searching projects: %w", err)
+	}
+
+	projects := make(map[int]project.SearchResponse)
+	for _, p := range *resp {
+		if p.CompanyID == companyID && strings.HasPrefix(p.Name, testAccPrefix) {
+			projects[p.ID] = p
+		}
+	}
+
+	for len(projects) > 0 {
+		parents := make(map[int]bool)
+		for _, p := range projects {
+			parents[p.ParentID] = true
+		}
+
+		var deleted int
+		for id, p := range projects {
+			if parents[id] {
+				continue
+			}
+
+			log.Printf("[INFO] Deleting project %d (%s)", id, p.Name)
+			_, err := client.Project.Delete(ctx, &project.DeleteRequest{
+				ID: strconv.Itoa(id),
+			})
+			if err != nil && !isNotFound(err) {
+				return fmt.Errorf("deleting project %d: %w", id, err)
+			}
+
+			delete(projects, id)
+			deleted++
+		}
+
+		if deleted == 0 {
+			return fmt.Errorf("the parents of %d projects form a cycle", len(projects))
+		}
+	}
+
+	return nil
+}
+
+func TestAccResourceProject_basic(t *testing.T) {
+	api := testAccIdefix(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV5ProviderFactories: testAccProtoV5ProviderFactories,
+		CheckDestroy:             testAccCheckProjectDestroy(api),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(api, testAccResourceProjectConfig(api, "tf-acc-project", "C-0001")),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckProjectExists(api, "idefix_project.test"),
+					resource.TestCheckResourceAttr("idefix_project.test", "name", "tf-acc-project"),
+					resource.TestCheckResourceAttr("idefix_project.test", "company_id", strconv.Itoa(api.CompanyID)),
+					resource.TestCheckResourceAttr("idefix_project.test", "contract_number", "C-0001"),
+				),
+			},
+			{
+				Config: testAccConfig(api, testAccResourceProjectConfig(api, "tf-acc-project-renamed", "C-0002")),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckProjectExists(api, "idefix_project.test"),
+					resource.TestCheckResourceAttr("idefix_project.test", "name", "tf-acc-project-renamed"),
+					resource.TestCheckResourceAttr("idefix_project.test", "contract_number", "C-0002"),
+				),
+			},
+			{
+				ResourceName:            "idefix_project.test",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"adopt_existing", "deletion_protection"},
+			},
+		},
+	})
+}
+
+func TestAccResourceProject_deletionProtection(t *testing.T) {
+	api := testAccIdefix(t)
+
+	config := fmt.Sprintf(`
+resource "idefix_project" "test" {
+  name            = "tf-acc-project"
+  company_id      = %d
+  contract_number = "C-0001"
+}
+`, api.CompanyID)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV5ProviderFactories: testAccProtoV5ProviderFactories,
+		CheckDestroy:             testAccCheckProjectDestroy(api),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(api, config),
+				Check:  resource.TestCheckResourceAttr("idefix_project.test", "deletion_protection", "true"),
+			},
+			{
+				Config:      testAccConfig(api, config),
+				Destroy:     true,
+				ExpectError: regexp.MustCompile("without setting deletion_protection=false"),
+			},
+			{
+				Config: testAccConfig(api, testAccResourceProjectConfig(api, "tf-acc-project", "C-0001")),
+			},
+		},
+	})
+}
+
+func TestAccResourceProject_adoptExisting(t *testing.T) {
+	api := testAccIdefix(t)
+
+	id := api.createProject(t, project.CreateRequest{
+		Name: "tf-acc-project",
+	})
+
+	resource.Test(t, resource.TestCase{
+		ProtoV5ProviderFactories: testAccProtoV5ProviderFactories,
+		CheckDestroy:             testAccCheckProjectDestroy(api),
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccConfig(api, testAccResourceProjectConfig(api, "tf-acc-project", "C-0001")),
+				ExpectError: regexp.MustCompile("already exists"),
+			},
+			{
+				Config: testAccConfig(api, fmt.Sprintf(`
+resource "idefix_project" "test" {
+  name                = "tf-acc-project"
+  company_id          = %d
+  contract_number     = "C-0001"
+  adopt_existing      = true
+  deletion_protection = false
+}
+`, api.CompanyID)),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("idefix_project.test", "id", id),
+					resource.TestCheckResourceAttr("idefix_project.test", "contract_number", "C-0001"),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceProjectCreate_adoptExisting(t *testing.T) {
+	api := testFakeIdefix(t)
+	s := testProviderServer(t, api.Client)
+	ctx := context.Background()
+
+	id := api.createProject(t, project.CreateRequest{
+		Name:           "tf-acc-project",
+		ContractNumber: "C-0001",
+	})
+
+	config := map[string]interface{}{
+		"name":            "tf-acc-project",
+		"company_id":      api.CompanyID,
+		"contract_number": "C-0002",
+	}
+	_, diags := s.create("idefix_project", config)
+	if d := testDiagnostic(diags, tfprotov5.DiagnosticSeverityError); d == nil || d.Summary != "Project already exists" || !d.Attribute.Equal(testAttributePath("name")) {
+		t.Errorf("creating a project which exists: got %s, want an error on name", testDiagnostics(diags))
+	}
+
+	config["adopt_existing"] = true
+	state, diags := s.create("idefix_project", config)
+	s.fatalDiagnostics("adopting", diags)
+	if d := testDiagnostic(diags, tfprotov5.DiagnosticSeverityWarning); d == nil || d.Summary != "Existing project adopted" {
+		t.Errorf("adopting: got %s, want a warning in the plan", testDiagnostics(diags))
+	}
+	if got := testAttrs(state)["id"]; got != id {
+		t.Errorf("adopted project %v, want %q", got, id)
+	}
+	if got := testAttrs(state)["contract_number"]; got != "C-0002" {
+		t.Errorf("adopted contract_number %v, want C-0002", got)
+	}
+
+	p, err := api.Client.Project.Read(ctx, &project.ReadRequest{ID: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ContractNumber != "C-0002" {
+		t.Errorf("adopting kept the contract number %q, want it overwritten", p.ContractNumber)
+	}
+
+	s.checkNoChanges("idefix_project", state, config)
+}
+
+func TestResourceProject_crud(t *testing.T) {
+	api := testIdefix(t)
+	s := testProviderServer(t, api.Client)
+	ctx := context.Background()
+
+	state, diags := s.create("idefix_project", map[string]interface{}{
+		"name":            "tf-acc-project",
+		"company_id":      api.CompanyID,
+		"contract_number": "C-0001",
+	})
+	s.fatalDiagnostics("creating", diags)
+	id, _ := testAttrs(state)["id"].(string)
+	if id == "" {
+		t.Fatal("creating: no ID set")
+	}
+	if got := testAttrs(state)["deletion_protection"]; got != true {
+		t.Errorf("created deletion_protection %v, want it true by default", got)
+	}
+
+	config := map[string]interface{}{
+		"name":            "tf-acc-project-renamed",
+		"company_id":      api.CompanyID,
+		"contract_number": "C-0002",
+	}
+	state, diags = s.apply("idefix_project", state, config)
+	s.fatalDiagnostics("updating", diags)
+
+	p, err := api.Client.Project.Read(ctx, &project.ReadRequest{
+		ID: id,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "tf-acc-project-renamed" || p.ContractNumber != "C-0002" {
+		t.Errorf("updated project is %q with contract %q", p.Name, p.ContractNumber)
+	}
+
+	state = s.checkNoChanges("idefix_project", state, config)
+
+	imported, diags := s.importState("idefix_project", id)
+	s.fatalDiagnostics("importing", diags)
+	want := map[string]interface{}{
+		"id":                  id,
+		"name":                "tf-acc-project-renamed",
+		"company_id":          api.CompanyID,
+		"adopt_existing":      false,
+		"deletion_protection": true,
+	}
+	for k, v := range want {
+		if got := testAttrs(imported)[k]; got != v {
+			t.Errorf("imported %s = %v, want %v", k, got, v)
+		}
+	}
+
+	if _, diags := s.destroy("idefix_project", state); !testHasError(diags) {
+		t.Fatal("deleting a protected project succeeded")
+	}
+
+	config["deletion_protection"] = false
+	state, diags = s.apply("idefix_project", state, config)
+	s.fatalDiagnostics("unprotecting", diags)
+
+	state, diags = s.destroy("idefix_project", state)
+	s.fatalDiagnostics("deleting", diags)
+	if !state.IsNull() {
+		t.Errorf("state %s left after deleting", testCanonical(state))
+	}
+	if _, err := api.Client.Project.Read(ctx, &project.ReadRequest{ID: id}); !isNotFound(err) {
+		t.Errorf("reading deleted project: got %v, want a not found error", err)
+	}
+}
+
+func TestResourceProjectStateUpgradeV0(t *testing.T) {
+	s := testProviderServer(t, &apiClient{})
+
+	state, diags := s.upgrade("idefix_project", 0, `{
+  "id": "1234",
+  "name": "tf-acc-project",
+  "company_id": 1,
+  "parent_id": 0,
+  "contract_number": "C-0001",
+  "adopt_existing": false,
+  "deletion_protection": true
+}`)
+	s.fatalDiagnostics("upgrading the state", diags)
+
+	attrs := testAttrs(state)
+	for k, want := range map[string]interface{}{"id": "1234", "parent_id": nil, "contract_number": "C-0001", "wbs_france": nil} {
+		if got := attrs[k]; got != want {
+			t.Errorf("upgraded %s = %v, want %v", k, got, want)
+		}
+	}
+}
+
+func testAccResourceProjectConfig(api *testAccAPI, name string, contractNumber string) string {
+	return fmt.Sprintf(`
+resource "idefix_project" "test" {
+  name                = %q
+  company_id          = %d
+  contract_number     = %q
+  deletion_protection = false
+}
+`, name, api.CompanyID, contractNumber)
+}
+
+func testAccCheckProjectExists(api *testAccAPI, name string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[name]
+		if !ok {
+			return fmt.Errorf("%s not found in state", name)
+		}
+
+		p, err := api.Client.Project.Read(context.Background(), &project.ReadRequest{
+			ID: rs.Primary.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("reading project %s: %w", rs.Primary.ID, err)
+		}
+		if p.Name != rs.Primary.Attributes["name"] {
+			return fmt.Errorf("project %s is named %q in Idefix, want %q", rs.Primary.ID, p.Name, rs.Primary.Attributes["name"])
+		}
+
+		return nil
+	}
+}
+
+func testAccCheckProjectDestroy(api *testAccAPI) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		for _, rs := range s.RootModule().Resources {
+			if rs.Type != "idefix_project" {
+				continue
+			}
+
+			_, err := api.Client.Project.Read(context.Background(), &project.ReadRequest{
+				ID: rs.Primary.ID,
+			})
+			if err == nil {
+				return fmt.Errorf("project %s still exists", rs.Primary.ID)
+			}
+			if !isNotFound(err) {
+				return fmt.Errorf("reading project %s: %w", rs.Primary.ID, err)
+			}
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,72 @@
+package idefix
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// timeoutsBlock returns the timeouts block of a resource. It has the
+// attributes of the block the SDK added for its schema.ResourceTimeout, so
+// that the states written by the SDK provider still decode.
+func timeoutsBlock() schema.SingleNestedBlock {
+	attributes := make(map[string]schema.Attribute)
+	for _, op := range []string{"create", "read", "update", "delete"} {
+		attributes[op] = schema.StringAttribute{
+			Optional:    true,
+			Validators:  []validator.String{durationValidator{}},
+			Description: fmt.Sprintf("The timeout of the %s operation, as a duration such as `30s` or `10m`.", op),
+		}
+	}
+
+	return schema.SingleNestedBlock{
+		Attributes: attributes,
+	}
+}
+
+// timeout returns the timeout of the operation op set in the timeouts block,
+// or def when it is not set.
+func timeout(timeouts types.Object, op string, def time.Duration) time.Duration {
+	if timeouts.IsNull() || timeouts.IsUnknown() {
+		return def
+	}
+
+	v, ok := timeouts.Attributes()[op].(types.String)
+	if !ok || v.IsNull() || v.IsUnknown() {
+		return def
+	}
+
+	// The value has been validated by durationValidator.
+	d, err := time.ParseDuration(v.ValueString())
+	if err != nil {
+		return def
+	}
+
+	return d
+}
+
+// durationValidator checks that a string is a duration time.ParseDuration
+// understands.
+type durationValidator struct{}
+
+func (v durationValidator) Description(ctx context.Context) string {
+	return "The value must be a duration such as 30s or 10m."
+}
+
+func (v durationValidator) MarkdownDescription(ctx context.Context) string {
+	return "The value must be a duration such as `30s` or `10m`."
+}
+
+func (v durationValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	if _, err := time.ParseDuration(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid duration", err.Error())
+	}
+}
@@ -0,0 +1,486 @@
+// Package cassette records the HTTP traffic between the provider and Idefix
+// and replays it, so that the tests recorded once against a real Idefix can be
+// run offline.
+//
+// The traffic is recorded at the http.RoundTripper level, by a reverse proxy
+// the provider is pointed at, so that the replay runs the goidefix client
+// against the very requests and responses of Idefix, its HTTP errors and JSON
+// decoding included. The secrets are scrubbed before anything is kept.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Mode tells whether a Recorder records or replays the interactions.
+type Mode string
+
+const (
+	// ModeRecord calls Idefix and records the interactions.
+	ModeRecord Mode = "record"
+	// ModeReplay answers the requests from the recorded interactions,
+	// without any network access.
+	ModeReplay Mode = "replay"
+)
+
+// Redacted replaces the secrets scrubbed from the cassettes.
+const Redacted = "REDACTED"
+
+// sensitiveKeys are the JSON keys, form fields and query parameters whose
+// values are scrubbed, compared case-insensitively.
+var sensitiveKeys = map[string]bool{
+	"login":         true,
+	"password":      true,
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"authorization": true,
+}
+
+// sensitiveHeaders are the headers whose values are scrubbed.
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+	"X-Auth-Token",
+}
+
+// Request is a recorded HTTP request.
+type Request struct {
+	Method string `json:"method"`
+	// URL is the path and query of the request, without the scheme and
+	// host of Idefix.
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// Response is a recorded HTTP response.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Interaction is an HTTP request to Idefix along with its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Cassette holds the interactions recorded for a test.
+type Cassette struct {
+	// Variables are values the test depends on, such as the ID of the company
+	// it was recorded with, so that the replay sends the same requests.
+	Variables    map[string]string `json:"variables,omitempty"`
+	Interactions []*Interaction    `json:"interactions"`
+}
+
+// Recorder records or replays the interactions of a cassette. It is an
+// http.RoundTripper, which sends the requests to Idefix with Transport and
+// records them in ModeRecord, and answers them from the cassette in
+// ModeReplay.
+type Recorder struct {
+	// Transport sends the requests in ModeRecord. When nil,
+	// http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	mode Mode
+	path string
+
+	mu       sync.Mutex
+	cassette Cassette
+	secrets  []string
+	used     map[*Interaction]bool
+}
+
+// New returns a recorder for the cassette stored at path. In ModeReplay the
+// cassette must exist.
+func New(path string, mode Mode) (*Recorder, error) {
+	r := &Recorder{
+		mode: mode,
+		path: path,
+		cassette: Cassette{
+			Variables: make(map[string]string),
+		},
+		used: make(map[*Interaction]bool),
+	}
+
+	switch mode {
+	case ModeRecord:
+	case ModeReplay:
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, &r.cassette); err != nil {
+			return nil, fmt.Errorf("reading cassette %s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown cassette mode %q", mode)
+	}
+
+	return r, nil
+}
+
+// Mode returns the mode of the recorder.
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Variable returns a variable of the cassette.
+func (r *Recorder) Variable(name string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.cassette.Variables[name]
+}
+
+// SetVariable stores a variable in the cassette.
+func (r *Recorder) SetVariable(name string, value string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cassette.Variables[name] = value
+}
+
+// AddSecret scrubs s wherever it appears in the recorded requests and
+// responses, such as the URL of Idefix or the credentials. The values of the
+// sensitive keys and headers are scrubbed anyway.
+func (r *Recorder) AddSecret(s string) {
+	if s == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.secrets = append(r.secrets, s)
+}
+
+// Save writes the recorded interactions to the cassette. It does nothing in
+// ModeReplay.
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(r.path, append(b, '\n'), 0o644)
+}
+
+// Handler returns a reverse proxy to target going through the recorder, for
+// the provider to be pointed at. target is not used in ModeReplay, and may be
+// nil then.
+func (r *Recorder) Handler(target *url.URL) http.Handler {
+	if target == nil {
+		target = &url.URL{Scheme: "http", Host: "idefix.invalid"}
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		director(req)
+		req.Host = target.Host
+		// The address of the tests is not forwarded to Idefix, nor recorded.
+		req.Header["X-Forwarded-For"] = nil
+	}
+	proxy.Transport = r
+	proxy.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+		http.Error(w, "cassette: "+err.Error(), http.StatusBadGateway)
+	}
+
+	return proxy
+}
+
+// RoundTrip records or replays req. A request or response which cannot be
+// scrubbed fails rather than being recorded.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	recReq, err := r.scrubRequest(req, body)
+	if err != nil {
+		return nil, fmt.Errorf("scrubbing the request %s %s: %w", req.Method, req.URL.Path, err)
+	}
+
+	if r.mode == ModeReplay {
+		return r.replay(req, recReq)
+	}
+
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err = readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	recResp, err := r.scrubResponse(resp, body)
+	if err != nil {
+		return nil, fmt.Errorf("scrubbing the response of %s %s: %w", req.Method, req.URL.Path, err)
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, &Interaction{
+		Request:  recReq,
+		Response: recResp,
+	})
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+// replay answers with the first interaction matching the request that has
+// not been used yet, in the order they were recorded. Once they have all been
+// used, the last one is answered again, as the provider may poll Idefix a
+// different number of times than during the recording. The requests are
+// matched on their method, URL and body, once scrubbed.
+func (r *Recorder) replay(req *http.Request, recReq Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var match *Interaction
+	for _, i := range r.cassette.Interactions {
+		if i.Request.Method != recReq.Method || i.Request.URL != recReq.URL || i.Request.Body != recReq.Body {
+			continue
+		}
+
+		match = i
+		if !r.used[i] {
+			break
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("cassette %s has no interaction for %s %s %s", r.path, recReq.Method, recReq.URL, recReq.Body)
+	}
+	r.used[match] = true
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", match.Response.StatusCode, http.StatusText(match.Response.StatusCode)),
+		StatusCode:    match.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        match.Response.Headers.Clone(),
+		Body:          io.NopCloser(strings.NewReader(match.Response.Body)),
+		ContentLength: int64(len(match.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// readBody reads the body b points to, and replaces it with a reader of the
+// same content.
+func readBody(b *io.ReadCloser) ([]byte, error) {
+	if *b == nil || *b == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(*b)
+	(*b).Close()
+	if err != nil {
+		return nil, err
+	}
+	*b = io.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
+
+func (r *Recorder) scrubRequest(req *http.Request, body []byte) (Request, error) {
+	query := req.URL.Query()
+	for k, values := range query {
+		for i, v := range values {
+			if sensitiveKeys[strings.ToLower(k)] {
+				values[i] = Redacted
+			} else {
+				values[i] = r.scrubString(v)
+			}
+		}
+	}
+
+	u := r.scrubString(req.URL.Path)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	scrubbed, err := r.scrubBody(req.Header.Get("Content-Type"), body)
+	if err != nil {
+		return Request{}, err
+	}
+
+	return Request{
+		Method:  req.Method,
+		URL:     u,
+		Headers: r.scrubHeaders(req.Header),
+		Body:    scrubbed,
+	}, nil
+}
+
+func (r *Recorder) scrubResponse(resp *http.Response, body []byte) (Response, error) {
+	scrubbed, err := r.scrubBody(resp.Header.Get("Content-Type"), body)
+	if err != nil {
+		return Response{}, err
+	}
+
+	return Response{
+		StatusCode: resp.StatusCode,
+		Headers:    r.scrubHeaders(resp.Header),
+		Body:       scrubbed,
+	}, nil
+}
+
+// scrubHeaders scrubs the headers of a request or response. Content-Length
+// is left out, as the body is recorded scrubbed.
+func (r *Recorder) scrubHeaders(h http.Header) http.Header {
+	scrubbed := make(http.Header, len(h))
+	for k, values := range h {
+		if k == "Content-Length" {
+			continue
+		}
+
+		for _, v := range values {
+			scrubbed.Add(k, r.scrubString(v))
+		}
+	}
+	for _, k := range sensitiveHeaders {
+		if _, ok := scrubbed[k]; ok {
+			scrubbed[k] = []string{Redacted}
+		}
+	}
+
+	return scrubbed
+}
+
+// scrubBody scrubs a JSON, form or text body, according to its content type.
+// A JSON body is recorded in its compact form, with its keys sorted. Any
+// other body cannot be scrubbed and is an error.
+func (r *Recorder) scrubBody(contentType string, body []byte) (string, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return "", nil
+	}
+
+	mediaType := ""
+	if contentType != "" {
+		var err error
+		mediaType, _, err = mime.ParseMediaType(contentType)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") || mediaType == "":
+		var value interface{}
+		if err := json.Unmarshal(body, &value); err != nil {
+			if mediaType == "" {
+				return "", errors.New("body without content type is not JSON")
+			}
+			return "", err
+		}
+
+		b, err := json.Marshal(r.scrubValue(value))
+
+		return string(b), err
+	case mediaType == "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return "", err
+		}
+		for k, values := range form {
+			for i, v := range values {
+				if sensitiveKeys[strings.ToLower(k)] {
+					r.learnSecret(v)
+					values[i] = Redacted
+				} else {
+					values[i] = r.scrubString(v)
+				}
+			}
+		}
+
+		return form.Encode(), nil
+	case strings.HasPrefix(mediaType, "text/"):
+		return r.scrubString(string(body)), nil
+	default:
+		return "", fmt.Errorf("cannot scrub a %s body", mediaType)
+	}
+}
+
+func (r *Recorder) scrubValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, value := range v {
+			if sensitiveKeys[strings.ToLower(k)] {
+				if s, ok := value.(string); ok {
+					r.learnSecret(s)
+				}
+				v[k] = Redacted
+				continue
+			}
+
+			v[k] = r.scrubValue(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = r.scrubValue(value)
+		}
+	case string:
+		return r.scrubString(v)
+	}
+
+	return v
+}
+
+// learnSecret scrubs from then on a value found under a sensitive key, such
+// as a token, wherever else it appears.
+func (r *Recorder) learnSecret(s string) {
+	if s == "" || s == Redacted || r.mode != ModeRecord {
+		return
+	}
+
+	r.AddSecret(s)
+}
+
+func (r *Recorder) scrubString(s string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+
+	return s
+}
@@ -0,0 +1,248 @@
+package cassette
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testIdefix serves a login and the reads of a CI whose status changes from
+// Creating to Active, and counts the requests it receives.
+func testIdefix(t *testing.T, requests *int) *url.URL {
+	t.Helper()
+
+	var reads int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+
+		var req map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/auth/login":
+			json.NewEncoder(w).Encode(map[string]string{"token": "secret-token", "message": "welcome user"})
+		case r.URL.Path == "/ci/read" && req["id"] == "1":
+			if r.Header.Get("Authorization") != "Bearer secret-token" {
+				http.Error(w, "not logged in", http.StatusUnauthorized)
+				return
+			}
+
+			reads++
+			status := "Active"
+			if reads == 1 {
+				status = "Creating"
+			}
+			json.NewEncoder(w).Encode(map[string]string{"id": "1", "status": status})
+		default:
+			http.Error(w, "CI "+req["id"]+" not found", http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return u
+}
+
+// testPost posts body to path on srv, and returns the status code and body of
+// the response.
+func testPost(t *testing.T, srv *httptest.Server, path string, body string, token string) (int, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.StatusCode, string(b)
+}
+
+func TestRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "test.json")
+
+	var requests int
+	target := testIdefix(t, &requests)
+
+	rec, err := New(path, ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.SetVariable("company_id", "1")
+	rec.AddSecret("user")
+	rec.AddSecret(target.Host)
+
+	srv := httptest.NewServer(rec.Handler(target))
+	code, body := testPost(t, srv, "/auth/login", `{"login":"user","password":"secret-password"}`, "")
+	if code != http.StatusOK || !strings.Contains(body, "secret-token") {
+		t.Fatalf("recording the login: got %d %s, want the token", code, body)
+	}
+	for _, want := range []string{"Creating", "Active"} {
+		code, body := testPost(t, srv, "/ci/read", `{"id":"1"}`, "secret-token")
+		if code != http.StatusOK || !strings.Contains(body, want) {
+			t.Errorf("recording the CI: got %d %s, want %s", code, body, want)
+		}
+	}
+	if code, _ := testPost(t, srv, "/ci/read", `{"id":"2"}`, "secret-token"); code != http.StatusNotFound {
+		t.Errorf("recording a missing CI: got %d, want 404", code)
+	}
+	srv.Close()
+
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"user", "secret-password", "secret-token", target.Host} {
+		if strings.Contains(string(b), secret) {
+			t.Errorf("cassette contains %q:\n%s", secret, b)
+		}
+	}
+
+	rec, err = New(path, ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.Variable("company_id"); got != "1" {
+		t.Errorf("got company_id %q, want 1", got)
+	}
+
+	recorded := requests
+	srv = httptest.NewServer(rec.Handler(nil))
+	defer srv.Close()
+
+	// The credentials and the token are matched once scrubbed.
+	code, body = testPost(t, srv, "/auth/login", `{"password":"other","login":"other"}`, "")
+	if code != http.StatusOK || !strings.Contains(body, `"token":"REDACTED"`) {
+		t.Errorf("replaying the login: got %d %s, want the redacted token", code, body)
+	}
+	for _, want := range []string{"Creating", "Active", "Active"} {
+		code, body := testPost(t, srv, "/ci/read", `{ "id": "1" }`, Redacted)
+		if code != http.StatusOK || !strings.Contains(body, want) {
+			t.Errorf("replaying the CI: got %d %s, want %s", code, body, want)
+		}
+	}
+	if code, body := testPost(t, srv, "/ci/read", `{"id":"2"}`, Redacted); code != http.StatusNotFound || !strings.Contains(body, "CI 2 not found") {
+		t.Errorf("replaying a missing CI: got %d %s, want a 404", code, body)
+	}
+	if code, _ := testPost(t, srv, "/ci/read", `{"id":"3"}`, Redacted); code != http.StatusBadGateway {
+		t.Errorf("replaying an unrecorded request: got %d, want 502", code)
+	}
+
+	if requests != recorded {
+		t.Errorf("replaying sent %d requests to Idefix", requests-recorded)
+	}
+}
+
+func TestRecorderScrub(t *testing.T) {
+	rec, err := New(filepath.Join(t.TempDir(), "test.json"), ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.AddSecret("idefix.example.com")
+
+	req, err := http.NewRequest(http.MethodPost, "https://idefix.example.com/auth/login?token=secret-token&page=1", strings.NewReader("login=user&password=secret-password&comment=on+idefix.example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Cookie", "session=secret-session")
+
+	got, err := rec.scrubRequest(req, []byte("login=user&password=secret-password&comment=on+idefix.example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Request{
+		Method: http.MethodPost,
+		URL:    "/auth/login?page=1&token=REDACTED",
+		Headers: http.Header{
+			"Content-Type": {"application/x-www-form-urlencoded"},
+			"Cookie":       {Redacted},
+		},
+		Body: "comment=on+REDACTED&login=REDACTED&password=REDACTED",
+	}
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(want)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("got %s, want %s", gotJSON, wantJSON)
+	}
+
+	// The password found in the form is scrubbed wherever else it appears.
+	if s := rec.scrubString("echo secret-password"); s != "echo REDACTED" {
+		t.Errorf("got %q, want the password scrubbed", s)
+	}
+}
+
+func TestRecorderUnscrubbable(t *testing.T) {
+	rec, err := New(filepath.Join(t.TempDir(), "test.json"), ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var requests int
+	target := testIdefix(t, &requests)
+
+	srv := httptest.NewServer(rec.Handler(target))
+	defer srv.Close()
+
+	for _, contentType := range []string{"application/octet-stream", ""} {
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/auth/login", strings.NewReader("\x00secret"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusBadGateway {
+			t.Errorf("recording an unscrubbable %q body: got %d, want 502", contentType, resp.StatusCode)
+		}
+	}
+
+	if requests != 0 {
+		t.Errorf("%d unscrubbable requests were sent to Idefix", requests)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(rec.cassette.Interactions); n != 0 {
+		t.Errorf("%d unscrubbable interactions recorded", n)
+	}
+}
@@ -0,0 +1,108 @@
+package fakeidefix
+
+import (
+	"context"
+
+	"github.com/marty-macfly/goidefix/services/ci"
+	"github.com/marty-macfly/goidefix/services/equipment"
+)
+
+// The reference lists the fake is seeded with. The defaults of the idefix_ci
+// resource are part of them, so that a CI can be created with its defaults.
+var (
+	CITypes = []ci.ListTypesResponse{
+		{ID: 41, Name: "Virtual machine"},
+		{ID: 42, Name: "Database"},
+	}
+	ServiceLevels = []ci.ListServiceLevelsResponse{
+		{ID: 100000080, Name: "Standard"},
+		{ID: 100000081, Name: "Premium"},
+	}
+	OutsourcingLevels = []ci.ListOutsourcingLevelsResponse{
+		{ID: 0, Name: "0 - Non-défini"},
+		{ID: 1, Name: "1 - Infogérance"},
+	}
+	Environments = []ci.ListEnvironmentsResponse{
+		{ID: 1, Name: "Production", Description: "Production environment"},
+		{ID: 2, Name: "Staging", Description: "Staging environment"},
+	}
+	Functions = []ci.ListFunctionsResponse{
+		{ID: 1, Name: "Web", Description: "Web server"},
+		{ID: 2, Name: "Database", Description: "Database server"},
+	}
+	CloudSubscriptions = []ci.ListCloudSubscriptionsResponse{
+		{ID: 1, Name: "Subscription", CompanyID: CompanyID},
+		{ID: 2, Name: "Other subscription", CompanyID: CompanyID + 1},
+	}
+	CloudProducts = []ci.ListCloudProductsResponse{
+		{ID: 1, Name: "Virtual machine"},
+	}
+	CloudRegions = []ci.ListCloudRegionsResponse{
+		{ID: 1, Name: "France Central"},
+		{ID: 2, Name: "West Europe"},
+	}
+	MonitoringTools = []equipment.ListMonitoringToolsResponse{
+		{ID: 1, Name: "Centreon"},
+	}
+	RequiredServices = []equipment.ListRequiredServicesResponse{
+		{ID: 1, Name: "Backup"},
+		{ID: 2, Name: "Antivirus"},
+	}
+)
+
+// list returns a copy of the given reference list.
+func list[T any](items []T) *[]T {
+	resp := make([]T, len(items))
+	copy(resp, items)
+
+	return &resp
+}
+
+func (s *CIService) ListTypes(ctx context.Context, req *ci.ListTypesRequest) (*[]ci.ListTypesResponse, error) {
+	return list(CITypes), nil
+}
+
+func (s *CIService) ListServiceLevels(ctx context.Context, req *ci.ListServiceLevelsRequest) (*[]ci.ListServiceLevelsResponse, error) {
+	return list(ServiceLevels), nil
+}
+
+func (s *CIService) ListOutsourcingLevels(ctx context.Context, req *ci.ListOutsourcingLevelsRequest) (*[]ci.ListOutsourcingLevelsResponse, error) {
+	return list(OutsourcingLevels), nil
+}
+
+func (s *CIService) ListEnvironments(ctx context.Context, req *ci.ListEnvironmentsRequest) (*[]ci.ListEnvironmentsResponse, error) {
+	return list(Environments), nil
+}
+
+func (s *CIService) ListFunctions(ctx context.Context, req *ci.ListFunctionsRequest) (*[]ci.ListFunctionsResponse, error) {
+	return list(Functions), nil
+}
+
+func (s *CIService) ListCloudSubscriptions(ctx context.Context, req *ci.ListCloudSubscriptionsRequest) (*[]ci.ListCloudSubscriptionsResponse, error) {
+	resp := make([]ci.ListCloudSubscriptionsResponse, 0)
+	for _, sub := range CloudSubscriptions {
+		if req.CompanyID != 0 && sub.CompanyID != req.CompanyID {
+			continue
+		}
+
+		resp = append(resp, sub)
+	}
+
+	return &resp, nil
+}
+
+func (s *CIService) ListCloudProducts(ctx context.Context, req *ci.ListCloudProductsRequest) (*[]ci.ListCloudProductsResponse, error) {
+	return list(CloudProducts), nil
+}
+
+func (s *CIService) ListCloudRegions(ctx context.Context, req *ci.ListCloudRegionsRequest) (*[]ci.ListCloudRegionsResponse, error) {
+	return list(CloudRegions), nil
+}
+
+func (s *EquipmentService) ListMonitoringTools(ctx context.Context, req *equipment.ListMonitoringToolsRequest) (*[]equipment.ListMonitoringToolsResponse, error) {
+	return list(MonitoringTools), nil
+}
+
+func (s *EquipmentService) ListRequiredServices(ctx context.Context, req *equipment.ListRequiredServicesRequest) (*[]equipment.ListRequiredServicesResponse, error) {
+	return list(RequiredServices), nil
+}
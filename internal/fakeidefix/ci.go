@@ -0,0 +1,346 @@
+package fakeidefix
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/marty-macfly/goidefix/services/ci"
+	"github.com/marty-macfly/goidefix/services/equipment"
+	"github.com/marty-macfly/goidefix/services/monitoring"
+)
+
+// AddMonitoringEvent attaches a monitoring event to the CI with the given ID
+// and returns the ID of the event.
+func (i *Idefix) AddMonitoringEvent(ciID string) string {
+	s := i.state
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	equipmentID, _ := strconv.Atoi(ciID)
+	id := strconv.Itoa(s.nextID())
+	s.events[id] = equipmentID
+
+	return id
+}
+
+// MonitoringEvents returns the number of monitoring events held by the fake.
+func (i *Idefix) MonitoringEvents() int {
+	s := i.state
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.events)
+}
+
+// RemoveCIDetails makes the reads of the service cloud, the key dates and the
+// services AT of the CI with the given ID fail with a 404 until they are
+// updated, the way Idefix may answer for a CI created without them.
+func (i *Idefix) RemoveCIDetails(ciID string) {
+	s := i.state
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r, ok := s.cis[ciID]; ok {
+		r.noDetails = true
+	}
+}
+
+// CIService is the fake of the goidefix ci service.
+type CIService struct {
+	*state
+}
+
+func (s *CIService) Create(ctx context.Context, req *ci.CreateRequest) (*ci.CreateResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := strconv.Itoa(s.nextID())
+	s.cis[id] = &ciRecord{
+		ci: ci.ReadResponse{
+			ID:              id,
+			Name:            req.Name,
+			TypeID:          strconv.Itoa(req.TypeID),
+			CompanyID:       req.CompanyID,
+			ProjectIDs:      joinIDs(req.ProjectIDs),
+			OutSourcingName: req.OutSourcingName,
+			ServiceLevelID:  req.ServiceLevelID,
+			Team:            req.Team,
+			IsOwnerLBN:      req.IsOwnerLBN,
+			Comment:         req.Comment,
+		},
+	}
+
+	return &ci.CreateResponse{
+		ID: id,
+	}, nil
+}
+
+func (s *CIService) Read(ctx context.Context, req *ci.ReadRequest) (*ci.ReadResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.cis[req.ID]
+	if !ok {
+		return nil, notFound("ci %s", req.ID)
+	}
+
+	resp := r.ci
+
+	return &resp, nil
+}
+
+func (s *CIService) Update(ctx context.Context, req *ci.UpdateRequest) (*ci.UpdateResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.cis[req.ID]
+	if !ok {
+		return nil, notFound("ci %s", req.ID)
+	}
+
+	r.ci.Name = req.Name
+	r.ci.TypeID = strconv.Itoa(req.TypeID)
+	r.ci.CompanyID = req.CompanyID
+	r.ci.ProjectIDs = joinIDs(req.ProjectIDs)
+	r.ci.OutSourcingName = req.OutSourcingName
+	r.ci.ServiceLevelID = req.ServiceLevelID
+	r.ci.Team = req.Team
+	r.ci.IsOwnerLBN = req.IsOwnerLBN
+	r.ci.Comment = req.Comment
+
+	return &ci.UpdateResponse{}, nil
+}
+
+func (s *CIService) Search(ctx context.Context, req *ci.SearchRequest) (*[]ci.SearchResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := make([]ci.SearchResponse, 0)
+	for _, r := range s.cis {
+		c := r.ci
+
+		if !containsFold(c.Name, req.Name) {
+			continue
+		}
+		if req.CompanyID != 0 && c.CompanyID != req.CompanyID {
+			continue
+		}
+		if req.ProjectID != 0 && !containsID(c.ProjectIDs, req.ProjectID) {
+			continue
+		}
+		if req.TypeID != 0 && c.TypeID != strconv.Itoa(req.TypeID) {
+			continue
+		}
+		if req.Team != "" && c.Team != req.Team {
+			continue
+		}
+		if req.ServiceLevelID != 0 && c.ServiceLevelID != req.ServiceLevelID {
+			continue
+		}
+
+		resp = append(resp, ci.SearchResponse{
+			ID:              c.ID,
+			Name:            c.Name,
+			TypeID:          c.TypeID,
+			CompanyID:       c.CompanyID,
+			ProjectIDs:      c.ProjectIDs,
+			OutSourcingName: c.OutSourcingName,
+			ServiceLevelID:  c.ServiceLevelID,
+			Team:            c.Team,
+		})
+	}
+	sort.Slice(resp, func(i, j int) bool {
+		a, _ := strconv.Atoi(resp[i].ID)
+		b, _ := strconv.Atoi(resp[j].ID)
+
+		return a < b
+	})
+
+	return &resp, nil
+}
+
+// containsID reports whether id is one of the comma separated IDs.
+func containsID(ids string, id int) bool {
+	for _, v := range strings.Split(ids, ",") {
+		if v == strconv.Itoa(id) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *CIService) ReadServiceCloud(ctx context.Context, req *ci.ReadServiceCloudRequest) (*ci.ReadServiceCloudResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.cis[req.ID]
+	if !ok {
+		return nil, notFound("ci %s", req.ID)
+	}
+	if r.noDetails {
+		return nil, notFound("service cloud of ci %s", req.ID)
+	}
+
+	resp := r.serviceCloud
+
+	return &resp, nil
+}
+
+func (s *CIService) UpdateServiceCloud(ctx context.Context, req *ci.UpdateServiceCloudRequest) (*ci.UpdateServiceCloudResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.cis[req.ID]
+	if !ok {
+		return nil, notFound("ci %s", req.ID)
+	}
+	r.noDetails = false
+
+	r.serviceCloud = ci.ReadServiceCloudResponse{
+		SubscriptionID: req.SubscriptionID,
+		ProductID:      req.ProductID,
+		RegionID:       req.RegionID,
+	}
+
+	return &ci.UpdateServiceCloudResponse{}, nil
+}
+
+func (s *CIService) ReadUseAndKeyDate(ctx context.Context, req *ci.ReadUseAndKeyDateRequest) (*ci.ReadUseAndKeyDateResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.cis[req.ID]
+	if !ok {
+		return nil, notFound("ci %s", req.ID)
+	}
+	if r.noDetails {
+		return nil, notFound("key dates of ci %s", req.ID)
+	}
+
+	resp := r.keyDates
+
+	return &resp, nil
+}
+
+func (s *CIService) UpdateUseAndKeyDate(ctx context.Context, req *ci.UpdateUseAndKeyDateRequest) (*ci.UpdateUseAndKeyDateResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.cis[req.ID]
+	if !ok {
+		return nil, notFound("ci %s", req.ID)
+	}
+	r.noDetails = false
+
+	r.keyDates = ci.ReadUseAndKeyDateResponse{
+		EnvironmentIDs: joinIDs(req.EnvironmentIDs),
+		FunctionIDs:    joinIDs(req.FunctionIDs),
+	}
+
+	return &ci.UpdateUseAndKeyDateResponse{}, nil
+}
+
+func (s *CIService) UpdatePlatform(ctx context.Context, req *ci.UpdatePlatformRequest) (*ci.UpdatePlatformResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.cis[req.ID]; !ok {
+		return nil, notFound("ci %s", req.ID)
+	}
+
+	return &ci.UpdatePlatformResponse{}, nil
+}
+
+// EquipmentService is the fake of the goidefix equipment service, whose
+// equipments are the CIs.
+type EquipmentService struct {
+	*state
+}
+
+func (s *EquipmentService) ReadAT(ctx context.Context, req *equipment.ReadATRequest) (*equipment.ReadATResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.cis[req.ID]
+	if !ok {
+		return nil, notFound("ci %s", req.ID)
+	}
+	if r.noDetails {
+		return nil, notFound("services AT of ci %s", req.ID)
+	}
+
+	resp := r.at
+
+	return &resp, nil
+}
+
+func (s *EquipmentService) UpdateAT(ctx context.Context, req *equipment.UpdateATRequest) (*equipment.UpdateATResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.cis[req.ID]
+	if !ok {
+		return nil, notFound("ci %s", req.ID)
+	}
+	r.noDetails = false
+
+	r.at = equipment.ReadATResponse{
+		RequiredServices: req.RequiredServices,
+		MonitoringTool:   req.MonitoringTool,
+	}
+
+	return &equipment.UpdateATResponse{}, nil
+}
+
+func (s *EquipmentService) Delete(ctx context.Context, req *equipment.DeleteRequest) (*equipment.DeleteResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.cis[req.ID]; !ok {
+		return nil, notFound("ci %s", req.ID)
+	}
+
+	delete(s.cis, req.ID)
+
+	return &equipment.DeleteResponse{}, nil
+}
+
+// MonitoringService is the fake of the goidefix monitoring service.
+type MonitoringService struct {
+	*state
+}
+
+func (s *MonitoringService) SearchEvents(ctx context.Context, req *monitoring.SearchEventsRequest) (*[]monitoring.SearchEventsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := make([]monitoring.SearchEventsResponse, 0)
+	for id, equipmentID := range s.events {
+		for _, v := range req.EquipmentIDs {
+			if v == equipmentID {
+				resp = append(resp, monitoring.SearchEventsResponse{
+					ID: id,
+				})
+			}
+		}
+	}
+	sort.Slice(resp, func(i, j int) bool { return resp[i].ID < resp[j].ID })
+
+	return &resp, nil
+}
+
+func (s *MonitoringService) DeleteEvents(ctx context.Context, req *monitoring.DeleteEventsRequest) (*monitoring.DeleteEventsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.events[req.ID]; !ok {
+		return nil, notFound("event %s", req.ID)
+	}
+
+	delete(s.events, req.ID)
+
+	return &monitoring.DeleteEventsResponse{}, nil
+}
@@ -0,0 +1,194 @@
+// Package fakeidefix implements in memory the services of the Idefix API used
+// by the provider, and serves them over HTTP with Server, so that it can be
+// tested with the goidefix client without a live Idefix nor any network
+// access. It works on the request and response types of goidefix and answers
+// with the same quirks as Idefix, such as string type IDs or comma separated
+// lists of IDs.
+package fakeidefix
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/marty-macfly/goidefix/services/authentification"
+	"github.com/marty-macfly/goidefix/services/ci"
+	"github.com/marty-macfly/goidefix/services/company"
+	"github.com/marty-macfly/goidefix/services/equipment"
+	"github.com/marty-macfly/goidefix/services/project"
+)
+
+const (
+	// Login and Password are the credentials accepted by the fake.
+	Login    = "terraform"
+	Password = "terraform"
+
+	// CompanyID is the ID of the company the fake is seeded with.
+	CompanyID = 1
+	// CompanyName is the name of the company the fake is seeded with.
+	CompanyName = "Linkbynet"
+)
+
+// Error is the error returned by the fake services, carrying the HTTP status
+// code Idefix answers with.
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Code, http.StatusText(e.Code), e.Message)
+}
+
+// StatusCode returns the HTTP status code of the error.
+func (e *Error) StatusCode() int {
+	return e.Code
+}
+
+func notFound(format string, a ...interface{}) error {
+	return &Error{Code: http.StatusNotFound, Message: fmt.Sprintf(format, a...)}
+}
+
+// Idefix is a fake Idefix keeping its state in memory, with the same services
+// as goidefix.Idefix. The zero value is not usable, use New.
+type Idefix struct {
+	Authentification *AuthentificationService
+	Company          *CompanyService
+	Project          *ProjectService
+	CI               *CIService
+	Equipment        *EquipmentService
+	Monitoring       *MonitoringService
+
+	state *state
+}
+
+// state is the content of the fake, shared by its services.
+type state struct {
+	mu        sync.Mutex
+	lastID    int
+	companies map[string]*company.ReadResponse
+	projects  map[string]*project.ReadResponse
+	cis       map[string]*ciRecord
+	events    map[string]int
+}
+
+// ciRecord holds a CI along with the details Idefix stores beside it.
+type ciRecord struct {
+	ci           ci.ReadResponse
+	serviceCloud ci.ReadServiceCloudResponse
+	keyDates     ci.ReadUseAndKeyDateResponse
+	at           equipment.ReadATResponse
+	// noDetails is set when Idefix answers the reads of the details with a
+	// 404, until they are updated.
+	noDetails bool
+}
+
+// New returns a fake Idefix seeded with a company and the reference lists.
+func New() *Idefix {
+	s := &state{
+		companies: map[string]*company.ReadResponse{
+			strconv.Itoa(CompanyID): {
+				ID:     strconv.Itoa(CompanyID),
+				Name:   CompanyName,
+				Code:   "LBN",
+				Status: "Active",
+			},
+		},
+		projects: make(map[string]*project.ReadResponse),
+		cis:      make(map[string]*ciRecord),
+		events:   make(map[string]int),
+		lastID:   1000,
+	}
+
+	return &Idefix{
+		Authentification: &AuthentificationService{s},
+		Company:          &CompanyService{s},
+		Project:          &ProjectService{s},
+		CI:               &CIService{s},
+		Equipment:        &EquipmentService{s},
+		Monitoring:       &MonitoringService{s},
+		state:            s,
+	}
+}
+
+func (s *state) nextID() int {
+	s.lastID++
+
+	return s.lastID
+}
+
+// AuthentificationService is the fake of the goidefix authentification
+// service.
+type AuthentificationService struct {
+	*state
+}
+
+func (s *AuthentificationService) Login(ctx context.Context, req *authentification.LoginRequest) (*authentification.LoginResponse, error) {
+	if req.Login != Login || req.Password != Password {
+		return nil, &Error{Code: http.StatusUnauthorized, Message: "invalid credentials"}
+	}
+
+	return &authentification.LoginResponse{
+		Token: "fake-token",
+	}, nil
+}
+
+// CompanyService is the fake of the goidefix company service.
+type CompanyService struct {
+	*state
+}
+
+func (s *CompanyService) Read(ctx context.Context, req *company.ReadRequest) (*company.ReadResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.companies[req.ID]
+	if !ok {
+		return nil, notFound("company %s", req.ID)
+	}
+	resp := *c
+
+	return &resp, nil
+}
+
+func (s *CompanyService) Search(ctx context.Context, req *company.SearchRequest) (*[]company.SearchResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := make([]company.SearchResponse, 0)
+	for _, c := range s.companies {
+		if !containsFold(c.Name, req.Name) {
+			continue
+		}
+
+		id, _ := strconv.Atoi(c.ID)
+		resp = append(resp, company.SearchResponse{
+			ID:     id,
+			Name:   c.Name,
+			Code:   c.Code,
+			Status: c.Status,
+		})
+	}
+	sort.Slice(resp, func(i, j int) bool { return resp[i].ID < resp[j].ID })
+
+	return &resp, nil
+}
+
+// containsFold reports whether substr is within s, ignoring the case.
+func containsFold(s string, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// joinIDs formats a list of IDs the way Idefix returns it.
+func joinIDs(ids []int) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.Itoa(id)
+	}
+
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,138 @@
+package fakeidefix
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/marty-macfly/goidefix/services/project"
+)
+
+// ProjectService is the fake of the goidefix project service.
+type ProjectService struct {
+	*state
+}
+
+func (s *ProjectService) Create(ctx context.Context, req *project.CreateRequest) (*project.CreateResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := &project.ReadResponse{
+		ID:             strconv.Itoa(s.nextID()),
+		Name:           req.Name,
+		CompanyID:      req.CompanyID,
+		ParentID:       req.ParentID,
+		WbsFrance:      req.WbsFrance,
+		WbsVietnam:     req.WbsVietnam,
+		WbsSingapour:   req.WbsSingapour,
+		WbsMaurice:     req.WbsMaurice,
+		WbsLuxembourg:  req.WbsLuxembourg,
+		WbsHongKong:    req.WbsHongKong,
+		WbsChine:       req.WbsChine,
+		WbsCanada:      req.WbsCanada,
+		WbsBelgique:    req.WbsBelgique,
+		ContractNumber: req.ContractNumber,
+		TypeName:       req.TypeName,
+		InvoiceType:    req.InvoiceType,
+		InitialBudget:  req.InitialBudget,
+	}
+	s.projects[p.ID] = p
+
+	return &project.CreateResponse{
+		ID: p.ID,
+	}, nil
+}
+
+func (s *ProjectService) Read(ctx context.Context, req *project.ReadRequest) (*project.ReadResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.projects[req.ID]
+	if !ok {
+		return nil, notFound("project %s", req.ID)
+	}
+	resp := *p
+
+	return &resp, nil
+}
+
+func (s *ProjectService) Update(ctx context.Context, req *project.UpdateRequest) (*project.UpdateResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.projects[req.ID]
+	if !ok {
+		return nil, notFound("project %s", req.ID)
+	}
+
+	p.Name = req.Name
+	p.CompanyID = req.CompanyID
+	p.ParentID = req.ParentID
+	p.WbsFrance = req.WbsFrance
+	p.WbsVietnam = req.WbsVietnam
+	p.WbsSingapour = req.WbsSingapour
+	p.WbsMaurice = req.WbsMaurice
+	p.WbsLuxembourg = req.WbsLuxembourg
+	p.WbsHongKong = req.WbsHongKong
+	p.WbsChine = req.WbsChine
+	p.WbsCanada = req.WbsCanada
+	p.WbsBelgique = req.WbsBelgique
+	p.ContractNumber = req.ContractNumber
+	p.TypeName = req.TypeName
+	p.InvoiceType = req.InvoiceType
+	p.InitialBudget = req.InitialBudget
+
+	return &project.UpdateResponse{}, nil
+}
+
+func (s *ProjectService) Delete(ctx context.Context, req *project.DeleteRequest) (*project.DeleteResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.projects[req.ID]; !ok {
+		return nil, notFound("project %s", req.ID)
+	}
+
+	delete(s.projects, req.ID)
+
+	return &project.DeleteResponse{}, nil
+}
+
+func (s *ProjectService) Search(ctx context.Context, req *project.SearchRequest) (*[]project.SearchResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := make([]project.SearchResponse, 0)
+	for _, p := range s.projects {
+		if !containsFold(p.Name, req.Name) {
+			continue
+		}
+		if req.CompanyID != 0 && p.CompanyID != req.CompanyID {
+			continue
+		}
+		if req.ParentID != 0 && p.ParentID != req.ParentID {
+			continue
+		}
+
+		id, _ := strconv.Atoi(p.ID)
+		resp = append(resp, project.SearchResponse{
+			ID:             id,
+			Name:           p.Name,
+			CompanyID:      p.CompanyID,
+			ParentID:       p.ParentID,
+			WbsFrance:      p.WbsFrance,
+			WbsVietnam:     p.WbsVietnam,
+			WbsSingapour:   p.WbsSingapour,
+			WbsMaurice:     p.WbsMaurice,
+			WbsLuxembourg:  p.WbsLuxembourg,
+			WbsHongKong:    p.WbsHongKong,
+			WbsChine:       p.WbsChine,
+			WbsCanada:      p.WbsCanada,
+			WbsBelgique:    p.WbsBelgique,
+			ContractNumber: p.ContractNumber,
+		})
+	}
+	sort.Slice(resp, func(i, j int) bool { return resp[i].ID < resp[j].ID })
+
+	return &resp, nil
+}
@@ -0,0 +1,170 @@
+package fakeidefix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// Routes maps the path of each call of the Idefix API used by the provider to
+// the goidefix method making it, as "Service.Method". The calls are POST
+// requests with the JSON encoding of the goidefix request as body, answered
+// with the JSON encoding of the goidefix response, or with the status code of
+// the error and its message as plain text.
+//
+// They must match the requests goidefix sends: a call which has no route here
+// is answered with 501 Not Implemented and reported by Unrouted, rather than
+// with a 404 which the provider would take for a missing object.
+var Routes = map[string]string{
+	"/auth/login": "Authentification.Login",
+
+	"/company/read":   "Company.Read",
+	"/company/search": "Company.Search",
+
+	"/project/create": "Project.Create",
+	"/project/read":   "Project.Read",
+	"/project/update": "Project.Update",
+	"/project/delete": "Project.Delete",
+	"/project/search": "Project.Search",
+
+	"/ci/create":                  "CI.Create",
+	"/ci/read":                    "CI.Read",
+	"/ci/update":                  "CI.Update",
+	"/ci/search":                  "CI.Search",
+	"/ci/service-cloud/read":      "CI.ReadServiceCloud",
+	"/ci/service-cloud/update":    "CI.UpdateServiceCloud",
+	"/ci/use-and-key-date/read":   "CI.ReadUseAndKeyDate",
+	"/ci/use-and-key-date/update": "CI.UpdateUseAndKeyDate",
+	"/ci/platform/update":         "CI.UpdatePlatform",
+	"/ci/types":                   "CI.ListTypes",
+	"/ci/service-levels":          "CI.ListServiceLevels",
+	"/ci/outsourcing-levels":      "CI.ListOutsourcingLevels",
+	"/ci/environments":            "CI.ListEnvironments",
+	"/ci/functions":               "CI.ListFunctions",
+	"/ci/cloud-subscriptions":     "CI.ListCloudSubscriptions",
+	"/ci/cloud-products":          "CI.ListCloudProducts",
+	"/ci/cloud-regions":           "CI.ListCloudRegions",
+
+	"/equipment/at/read":           "Equipment.ReadAT",
+	"/equipment/at/update":         "Equipment.UpdateAT",
+	"/equipment/delete":            "Equipment.Delete",
+	"/equipment/monitoring-tools":  "Equipment.ListMonitoringTools",
+	"/equipment/required-services": "Equipment.ListRequiredServices",
+
+	"/monitoring/events/search": "Monitoring.SearchEvents",
+	"/monitoring/events/delete": "Monitoring.DeleteEvents",
+}
+
+// Server serves a fake Idefix over HTTP, so that the provider is tested with
+// the goidefix client, its HTTP requests and its JSON decoding included. The
+// zero value is not usable, use NewServer.
+type Server struct {
+	*httptest.Server
+	*Idefix
+
+	mu       sync.Mutex
+	unrouted []string
+}
+
+// NewServer starts a fake Idefix seeded like New. The caller must call Close
+// when done.
+func NewServer() *Server {
+	s := &Server{
+		Idefix: New(),
+	}
+
+	handlers := map[string]http.Handler{
+		"Authentification.Login": handle(s.Authentification.Login),
+
+		"Company.Read":   handle(s.Company.Read),
+		"Company.Search": handle(s.Company.Search),
+
+		"Project.Create": handle(s.Project.Create),
+		"Project.Read":   handle(s.Project.Read),
+		"Project.Update": handle(s.Project.Update),
+		"Project.Delete": handle(s.Project.Delete),
+		"Project.Search": handle(s.Project.Search),
+
+		"CI.Create":                 handle(s.CI.Create),
+		"CI.Read":                   handle(s.CI.Read),
+		"CI.Update":                 handle(s.CI.Update),
+		"CI.Search":                 handle(s.CI.Search),
+		"CI.ReadServiceCloud":       handle(s.CI.ReadServiceCloud),
+		"CI.UpdateServiceCloud":     handle(s.CI.UpdateServiceCloud),
+		"CI.ReadUseAndKeyDate":      handle(s.CI.ReadUseAndKeyDate),
+		"CI.UpdateUseAndKeyDate":    handle(s.CI.UpdateUseAndKeyDate),
+		"CI.UpdatePlatform":         handle(s.CI.UpdatePlatform),
+		"CI.ListTypes":              handle(s.CI.ListTypes),
+		"CI.ListServiceLevels":      handle(s.CI.ListServiceLevels),
+		"CI.ListOutsourcingLevels":  handle(s.CI.ListOutsourcingLevels),
+		"CI.ListEnvironments":       handle(s.CI.ListEnvironments),
+		"CI.ListFunctions":          handle(s.CI.ListFunctions),
+		"CI.ListCloudSubscriptions": handle(s.CI.ListCloudSubscriptions),
+		"CI.ListCloudProducts":      handle(s.CI.ListCloudProducts),
+		"CI.ListCloudRegions":       handle(s.CI.ListCloudRegions),
+
+		"Equipment.ReadAT":               handle(s.Equipment.ReadAT),
+		"Equipment.UpdateAT":             handle(s.Equipment.UpdateAT),
+		"Equipment.Delete":               handle(s.Equipment.Delete),
+		"Equipment.ListMonitoringTools":  handle(s.Equipment.ListMonitoringTools),
+		"Equipment.ListRequiredServices": handle(s.Equipment.ListRequiredServices),
+
+		"Monitoring.SearchEvents": handle(s.Monitoring.SearchEvents),
+		"Monitoring.DeleteEvents": handle(s.Monitoring.DeleteEvents),
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, ok := handlers[Routes[r.URL.Path]]
+		if !ok || r.Method != http.MethodPost {
+			s.mu.Lock()
+			s.unrouted = append(s.unrouted, r.Method+" "+r.URL.Path)
+			s.mu.Unlock()
+
+			http.Error(w, "fakeidefix: no route for "+r.Method+" "+r.URL.Path, http.StatusNotImplemented)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	}))
+
+	return s
+}
+
+// Unrouted returns the requests the server received and had no route for.
+func (s *Server) Unrouted() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.unrouted...)
+}
+
+// handle serves f, a method of the fake services, decoding its request from
+// the JSON body and encoding its response.
+func handle[Req any, Resp any](f func(context.Context, *Req) (*Resp, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := new(Req)
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil && err != io.EOF {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		resp, err := f(r.Context(), req)
+		if err != nil {
+			code := http.StatusInternalServerError
+			var e *Error
+			if errors.As(err, &e) {
+				code = e.Code
+			}
+
+			http.Error(w, err.Error(), code)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/plugin"
+	"context"
+	"flag"
+	"log"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tf5server"
 	"github.com/marty-macfly/terraform-provider-idefix/idefix"
 )
 
@@ -10,9 +13,27 @@ import (
 //go:generate go run github.com/hashicorp/terraform-plugin-docs/cmd/tfplugindocs
 
 func main() {
-	plugin.Serve(&plugin.ServeOpts{
-		ProviderFunc: func() *schema.Provider {
-			return idefix.Provider()
-		},
-	})
+	var debug bool
+	var address string
+
+	flag.BoolVar(&debug, "debug", false, "Start the provider in debug mode, so that a debugger such as delve can be attached. The TF_REATTACH_PROVIDERS value to use is printed on startup.")
+	flag.StringVar(&address, "address", "registry.terraform.io/linkbynet/idefix", "The address of the provider, as written in the source of the required_providers block.")
+	flag.Parse()
+
+	ctx := context.Background()
+
+	providerServer, err := idefix.ProtoV5ProviderServerFactory(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var serveOpts []tf5server.ServeOpt
+	if debug {
+		serveOpts = append(serveOpts, tf5server.WithManagedDebug())
+	}
+
+	err = tf5server.Serve(address, providerServer, serveOpts...)
+	if err != nil {
+		log.Fatal(err)
+	}
 }